@@ -14,9 +14,12 @@ import (
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/redis/go-redis/v9"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/background"
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/config"
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/handler"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/lifecycle"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/reminder"
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/repository"
 	"golang.org/x/crypto/bcrypt"
 
@@ -151,6 +154,15 @@ func main() {
 	}
 	handler.RegisterRoutes()
 
+	/**********************************************
+	 * 启动后台任务
+	 **********************************************/
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	defer bgCancel()
+
+	go background.Every(bgCtx, "advance-schedule-plan-status", time.Duration(cfg.Lifecycle.Interval)*time.Second, lifecycle.Advance(repo))
+	go background.Every(bgCtx, "send-submission-reminders", time.Duration(cfg.SubmissionReminder.Interval)*time.Second, reminder.Send(repo, cfg, ch))
+
 	/**********************************************
 	 * 启动 HTTP 服务器
 	 **********************************************/
@@ -176,6 +188,7 @@ func main() {
 
 	<-quit
 	logger.Info("正在关闭服务器...")
+	bgCancel()
 
 	ctx, cancel = context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
 	defer cancel()
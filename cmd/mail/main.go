@@ -186,6 +186,32 @@ func main() {
 						continue
 					}
 					mail.Subject("ECNC 假勤系统 - 修改邮箱")
+				case "shift_swap":
+					tmpl, err := template.ParseFiles("./templates/shift_swap_email.html")
+					if err != nil {
+						logger.Error("无法解析邮件模板", slog.String("error", err.Error()))
+						_ = msg.Nack(false, false)
+						continue
+					}
+					if err := mail.SetBodyHTMLTemplate(tmpl, mailMessage.Data); err != nil {
+						logger.Error("无法设置邮件正文", slog.String("error", err.Error()))
+						_ = msg.Nack(false, false)
+						continue
+					}
+					mail.Subject("ECNC 假勤系统 - 换班通知")
+				case "submission_reminder":
+					tmpl, err := template.ParseFiles("./templates/submission_reminder_email.html")
+					if err != nil {
+						logger.Error("无法解析邮件模板", slog.String("error", err.Error()))
+						_ = msg.Nack(false, false)
+						continue
+					}
+					if err := mail.SetBodyHTMLTemplate(tmpl, mailMessage.Data); err != nil {
+						logger.Error("无法设置邮件正文", slog.String("error", err.Error()))
+						_ = msg.Nack(false, false)
+						continue
+					}
+					mail.Subject("ECNC 假勤系统 - 提交空闲时间提醒")
 				default:
 					logger.Error("不支持的邮件类型", slog.String("type", mailMessage.Type))
 					_ = msg.Nack(false, false)
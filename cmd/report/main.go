@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/config"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/payroll"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/repository"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+func main() {
+	var month string
+	var format string
+	var output string
+
+	flag.StringVar(&month, "month", time.Now().AddDate(0, -1, 0).Format("2006-01"), "要统计的月份，格式为 YYYY-MM，默认为上个月")
+	flag.StringVar(&format, "format", "csv", "导出格式 (csv, xlsx)")
+	flag.StringVar(&output, "o", "", "输出文件路径，默认为 工时工资表_<月份>.<格式>")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	m, err := time.Parse("2006-01", month)
+	if err != nil {
+		logger.Error("月份格式错误，应为 YYYY-MM", "month", month)
+		os.Exit(1)
+	}
+	if format != "csv" && format != "xlsx" {
+		logger.Error("不支持的导出格式", "format", format)
+		os.Exit(1)
+	}
+	if output == "" {
+		output = fmt.Sprintf("工时工资表_%s.%s", month, format)
+	}
+
+	// 读取配置文件
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Error("无法读取配置文件", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// 创建数据库连接池
+	dbpool, err := sql.Open("pgx", cfg.Database.DSN)
+	if err != nil {
+		logger.Error("无法创建数据库连接池", "error", err)
+		os.Exit(1)
+	}
+	defer dbpool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Database.ConnectTimeout)*time.Second)
+	defer cancel()
+
+	if err := dbpool.PingContext(ctx); err != nil {
+		logger.Error("无法连接到数据库", "error", err)
+		os.Exit(1)
+	}
+
+	repo := repository.NewRepository(cfg, dbpool)
+
+	report, err := payroll.Generate(repo, cfg, m)
+	if err != nil {
+		logger.Error("无法生成工时报表", "error", err)
+		os.Exit(1)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		logger.Error("无法创建输出文件", "error", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	switch format {
+	case "xlsx":
+		err = report.WriteXLSX(f)
+	default:
+		err = report.WriteCSV(f)
+	}
+	if err != nil {
+		logger.Error("无法导出工时报表", "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("已导出工时报表", "month", report.Month, "users", len(report.Rows), "totalHours", report.TotalHours, "totalPay", report.TotalPay, "output", output)
+}
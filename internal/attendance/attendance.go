@@ -0,0 +1,92 @@
+package attendance
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/config"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// Rules 考勤规则
+type Rules struct {
+	CheckInWindow   time.Duration // 在开始时间前后多久之内可以签到
+	CheckOutWindow  time.Duration // 在结束时间之后多久之内还可以签退
+	LateGrace       time.Duration // 开始时间之后多久签到才算迟到
+	EarlyLeaveGrace time.Duration // 结束时间之前多久签退才算早退
+}
+
+// Evaluate 根据签到签退时间计算考勤状态，如果黑心手动修正过则以修正的为准
+func Evaluate(instance *domain.ShiftInstance, now time.Time, rules Rules) domain.AttendanceStatus {
+	if instance.StatusOverride != nil {
+		return *instance.StatusOverride
+	}
+
+	if instance.CheckInAt == nil {
+		if now.Before(instance.StartAt.Add(rules.CheckInWindow)) {
+			return domain.AttendanceStatusPending
+		}
+		return domain.AttendanceStatusAbsent
+	}
+
+	if instance.CheckOutAt == nil {
+		if now.Before(instance.EndAt.Add(rules.CheckOutWindow)) {
+			return domain.AttendanceStatusOnDuty
+		}
+		return domain.AttendanceStatusMissingCheckOut
+	}
+
+	late := instance.CheckInAt.After(instance.StartAt.Add(rules.LateGrace))
+	early := instance.CheckOutAt.Before(instance.EndAt.Add(-rules.EarlyLeaveGrace))
+	switch {
+	case late && early:
+		return domain.AttendanceStatusLateAndEarlyLeave
+	case late:
+		return domain.AttendanceStatusLate
+	case early:
+		return domain.AttendanceStatusEarlyLeave
+	default:
+		return domain.AttendanceStatusNormal
+	}
+}
+
+// CheckIn 签到，只能在开始时间前后的窗口内签到
+func CheckIn(instance *domain.ShiftInstance, now time.Time, rules Rules) error {
+	if instance.CheckInAt != nil {
+		return errors.New("已经签到过了")
+	}
+	if now.Before(instance.StartAt.Add(-rules.CheckInWindow)) {
+		return errors.New("还没到签到时间")
+	}
+	if now.After(instance.StartAt.Add(rules.CheckInWindow)) {
+		return errors.New("已超过签到时间，请联系黑心补签")
+	}
+
+	instance.CheckInAt = &now
+	return nil
+}
+
+// CheckOut 签退，签到之后到结束时间之后的窗口内都可以签退，结束前签退视为早退
+func CheckOut(instance *domain.ShiftInstance, now time.Time, rules Rules) error {
+	if instance.CheckInAt == nil {
+		return errors.New("请先签到")
+	}
+	if instance.CheckOutAt != nil {
+		return errors.New("已经签退过了")
+	}
+	if now.After(instance.EndAt.Add(rules.CheckOutWindow)) {
+		return errors.New("已超过签退时间，请联系黑心补签")
+	}
+
+	instance.CheckOutAt = &now
+	return nil
+}
+
+func RulesFromConfig(cfg *config.Config) Rules {
+	return Rules{
+		CheckInWindow:   time.Duration(cfg.Attendance.CheckInWindow) * time.Minute,
+		CheckOutWindow:  time.Duration(cfg.Attendance.CheckOutWindow) * time.Minute,
+		LateGrace:       time.Duration(cfg.Attendance.LateGrace) * time.Minute,
+		EarlyLeaveGrace: time.Duration(cfg.Attendance.EarlyLeaveGrace) * time.Minute,
+	}
+}
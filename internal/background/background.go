@@ -0,0 +1,36 @@
+package background
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Every 每隔 interval 执行一次 job，启动时会先执行一次，直到 ctx 被取消
+// job 返回的错误只会被记录下来，不会中断后续的执行
+func Every(ctx context.Context, name string, interval time.Duration, job func(now time.Time) error) {
+	run := func() {
+		defer func() {
+			if err := recover(); err != nil {
+				slog.Error("后台任务崩溃", "job", name, "error", err)
+			}
+		}()
+		if err := job(time.Now()); err != nil {
+			slog.Error("后台任务执行失败", "job", name, "error", err)
+		}
+	}
+
+	run()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run()
+		}
+	}
+}
@@ -66,6 +66,36 @@ type Config struct {
 	NewUser struct {
 		PasswordLength int `env:"PASSWORD_LENGTH" envDefault:"12"`
 	} `envPrefix:"NEW_USER_"`
+	ShiftSwap struct {
+		RequireApproval bool `env:"REQUIRE_APPROVAL" envDefault:"true"` // 对方接受后是否还需要黑心审批
+	} `envPrefix:"SHIFT_SWAP_"`
+	Attendance struct {
+		CheckInWindow   int `env:"CHECK_IN_WINDOW" envDefault:"30"`  // 分钟，开始时间前后多久之内可以签到
+		CheckOutWindow  int `env:"CHECK_OUT_WINDOW" envDefault:"30"` // 分钟，结束时间之后多久之内还可以签退
+		LateGrace       int `env:"LATE_GRACE" envDefault:"5"`        // 分钟，开始时间之后多久签到才算迟到
+		EarlyLeaveGrace int `env:"EARLY_LEAVE_GRACE" envDefault:"5"` // 分钟，结束时间之前多久签退才算早退
+	} `envPrefix:"ATTENDANCE_"`
+	Payroll struct {
+		NormalAssistantRate float64 `env:"NORMAL_ASSISTANT_RATE" envDefault:"0"` // 元/小时
+		SeniorAssistantRate float64 `env:"SENIOR_ASSISTANT_RATE" envDefault:"0"`
+		BlackCoreRate       float64 `env:"BLACK_CORE_RATE" envDefault:"0"`
+	} `envPrefix:"PAYROLL_"`
+	Lifecycle struct {
+		Interval int `env:"INTERVAL" envDefault:"60"` // 秒，自动推进排班计划状态的检查间隔
+	} `envPrefix:"LIFECYCLE_"`
+	SubmissionReminder struct {
+		Interval    int   `env:"INTERVAL" envDefault:"300"`                       // 秒，检查是否需要发送提醒的间隔
+		HoursBefore []int `env:"HOURS_BEFORE" envDefault:"48,6" envSeparator:","` // 在截止时间之前多少小时提醒还没有提交的用户
+	} `envPrefix:"SUBMISSION_REMINDER_"`
+	LateSubmission struct {
+		Enabled bool `env:"ENABLED" envDefault:"false"` // 截止之后是否还允许逾期提交，逾期提交需要黑心审批之后才会参与排班
+	} `envPrefix:"LATE_SUBMISSION_"`
+	Upload struct {
+		MaxSize int64 `env:"MAX_SIZE" envDefault:"5242880"` // 5 MB
+	} `envPrefix:"UPLOAD_"`
+	Print struct {
+		FontPath string `env:"FONT_PATH"` // 导出 PDF 时使用的中文 TTF 字体，为空则不支持导出 PDF
+	} `envPrefix:"PRINT_"`
 }
 
 func LoadConfig() (*Config, error) {
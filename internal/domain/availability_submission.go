@@ -2,6 +2,14 @@ package domain
 
 import "time"
 
+type LateSubmissionStatus string
+
+const (
+	LateSubmissionStatusPending  LateSubmissionStatus = "待审批"
+	LateSubmissionStatusApproved LateSubmissionStatus = "已批准"
+	LateSubmissionStatusRejected LateSubmissionStatus = "已驳回"
+)
+
 type AvailabilitySubmissionItem struct {
 	ShiftID int64   `json:"shiftID"`
 	Days    []int32 `json:"days"`
@@ -12,6 +20,40 @@ type AvailabilitySubmission struct {
 	SchedulePlanID int64                        `json:"schedulePlanID"`
 	UserID         int64                        `json:"userID"`
 	Items          []AvailabilitySubmissionItem `json:"items"`
+	Revision       int32                        `json:"revision"`     // 同一个用户在同一个排班计划中的第几次提交
+	SupersededAt   *time.Time                   `json:"supersededAt"` // 被新的提交取代的时间，最新的提交为 null
+	LateStatus     *LateSubmissionStatus        `json:"lateStatus"`   // 逾期提交的审批状态，按时提交为 null，只有已批准的逾期提交才会参与排班
+	LateReviewerID *int64                       `json:"lateReviewerID"`
+	Extension      *SubmissionExtension         `json:"extension,omitempty"` // 只在黑心查看提交列表时返回该用户被延长的截止时间
 	CreatedAt      time.Time                    `json:"createdAt"`
 	Version        int32                        `json:"-"`
 }
+
+// AvailabilitySubmissionDiff 相邻两次提交之间的差异
+type AvailabilitySubmissionDiff struct {
+	Added   []AvailabilitySubmissionItem `json:"added"`
+	Removed []AvailabilitySubmissionItem `json:"removed"`
+}
+
+type AvailabilitySubmissionRevision struct {
+	Submission *AvailabilitySubmission    `json:"submission"`
+	Diff       AvailabilitySubmissionDiff `json:"diff"` // 与上一次提交相比的差异，第一次提交时所有班次都视为新增
+}
+
+// UnmappedSubmissionSlot 从之前的排班计划复制空闲时间时无法对应到新模板的 (shift, day)
+type UnmappedSubmissionSlot struct {
+	ShiftID   int64  `json:"shiftID"` // 之前模板中的班次
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+	Location  string `json:"location"`
+	Day       int32  `json:"day"`
+	Reason    string `json:"reason"`
+}
+
+// AvailabilitySubmissionDraft 预先填好的空闲时间，需要用户确认之后再提交
+type AvailabilitySubmissionDraft struct {
+	SchedulePlanID       int64                        `json:"schedulePlanID"`
+	SourceSchedulePlanID int64                        `json:"sourceSchedulePlanID"`
+	Items                []AvailabilitySubmissionItem `json:"items"`
+	Unmapped             []UnmappedSubmissionSlot     `json:"unmapped"`
+}
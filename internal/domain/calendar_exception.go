@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+type CalendarExceptionType string
+
+const (
+	CalendarExceptionTypeClosed  CalendarExceptionType = "停班" // 当天不排班，例如法定节假日
+	CalendarExceptionTypeWorkday CalendarExceptionType = "调休" // 当天按照 WorkAsDay 对应的星期排班，例如调休上班的周末
+)
+
+// CalendarException 排班计划中不按照每周规律排班的日期
+type CalendarException struct {
+	ID             int64                 `json:"id"`
+	SchedulePlanID int64                 `json:"schedulePlanID"`
+	Date           time.Time             `json:"date"` // 只有日期部分有意义
+	Type           CalendarExceptionType `json:"type"`
+	WorkAsDay      *int32                `json:"workAsDay"` // 只有调休时才有值
+	Note           string                `json:"note"`
+	CreatedAt      time.Time             `json:"createdAt"`
+	Version        int32                 `json:"-"`
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+type LeaveRequestStatus string
+
+const (
+	LeaveRequestStatusPending   LeaveRequestStatus = "待审批"
+	LeaveRequestStatusApproved  LeaveRequestStatus = "已批准"
+	LeaveRequestStatusRejected  LeaveRequestStatus = "已驳回"
+	LeaveRequestStatusCancelled LeaveRequestStatus = "已取消"
+)
+
+type LeaveRequest struct {
+	ID             int64              `json:"id"`
+	SchedulePlanID int64              `json:"schedulePlanID"`
+	UserID         int64              `json:"userID"`
+	ShiftID        int64              `json:"shiftID"`
+	Date           time.Time          `json:"date"` // 只有日期部分有意义
+	Reason         string             `json:"reason"`
+	Status         LeaveRequestStatus `json:"status"`
+	ReviewerID     *int64             `json:"reviewerID"`
+	SubstituteID   *int64             `json:"substituteID"` // 批准后由黑心指定的代班人
+	CreatedAt      time.Time          `json:"createdAt"`
+	UpdatedAt      time.Time          `json:"updatedAt"`
+	Version        int32              `json:"-"`
+}
+
+// SubstituteCandidate 代班候选人，按照当前的工作量从低到高排序
+type SubstituteCandidate struct {
+	UserID          int64  `json:"userID"`
+	Username        string `json:"username"`
+	FullName        string `json:"fullName"`
+	Role            Role   `json:"role"`
+	ScheduledCount  int    `json:"scheduledCount"`  // 排班结果中每周的班次数
+	SubstituteCount int    `json:"substituteCount"` // 在该排班计划中已经代过的班次数
+	Load            int    `json:"load"`
+}
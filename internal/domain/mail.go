@@ -23,3 +23,23 @@ type ChangeEmailMailData struct {
 	OTP        string `json:"otp"`
 	Expiration int    `json:"expiration"`
 }
+
+type ShiftSwapMailData struct {
+	FullName         string `json:"fullName"`
+	Title            string `json:"title"`
+	PlanName         string `json:"planName"`
+	Type             string `json:"type"`
+	Status           string `json:"status"`
+	ProposerName     string `json:"proposerName"`
+	CounterpartyName string `json:"counterpartyName"`
+	Slot             string `json:"slot"`
+	CounterpartySlot string `json:"counterpartySlot"` // 只有交换时才有值
+	Reason           string `json:"reason"`
+}
+
+type SubmissionReminderMailData struct {
+	FullName          string `json:"fullName"`
+	PlanName          string `json:"planName"`
+	SubmissionEndTime string `json:"submissionEndTime"`
+	HoursLeft         int    `json:"hoursLeft"`
+}
@@ -2,15 +2,29 @@ package domain
 
 import "time"
 
+type SchedulePlanStatus string
+
+const (
+	SchedulePlanStatusDraft      SchedulePlanStatus = "草稿"
+	SchedulePlanStatusOpen       SchedulePlanStatus = "收集中" // 开放提交空闲时间
+	SchedulePlanStatusClosed     SchedulePlanStatus = "已截止" // 截止提交，等待排班
+	SchedulePlanStatusScheduling SchedulePlanStatus = "排班中"
+	SchedulePlanStatusPublished  SchedulePlanStatus = "已发布"
+	SchedulePlanStatusActive     SchedulePlanStatus = "生效中"
+	SchedulePlanStatusArchived   SchedulePlanStatus = "已归档"
+)
+
 type SchedulePlan struct {
-	ID                  int64     `json:"id"`
-	Name                string    `json:"name"`
-	Description         string    `json:"description"`
-	SubmissionStartTime time.Time `json:"submissionStartTime"`
-	SubmissionEndTime   time.Time `json:"submissionEndTime"`
-	ActiveStartTime     time.Time `json:"activeStartTime"`
-	ActiveEndTime       time.Time `json:"activeEndTime"`
-	ScheduleTemplateID  int64     `json:"scheduleTemplateID"`
-	CreatedAt           time.Time `json:"createdAt"`
-	Version             int32     `json:"-"`
+	ID                  int64              `json:"id"`
+	Name                string             `json:"name"`
+	Description         string             `json:"description"`
+	SubmissionStartTime time.Time          `json:"submissionStartTime"`
+	SubmissionEndTime   time.Time          `json:"submissionEndTime"`
+	ActiveStartTime     time.Time          `json:"activeStartTime"`
+	ActiveEndTime       time.Time          `json:"activeEndTime"`
+	ScheduleTemplateID  int64              `json:"scheduleTemplateID"`
+	RotationStartDate   *time.Time         `json:"rotationStartDate"` // 轮换周期第一周所在的日期，为空时以生效开始时间所在的周为第一周
+	Status              SchedulePlanStatus `json:"status"`
+	CreatedAt           time.Time          `json:"createdAt"`
+	Version             int32              `json:"-"`
 }
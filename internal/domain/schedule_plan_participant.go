@@ -0,0 +1,9 @@
+package domain
+
+// SchedulePlanParticipants 排班计划的参与名单
+// 没有设置名单（Restricted 为 false）时所有在职用户都可以参与，Users 为实际可以参与的在职用户
+type SchedulePlanParticipants struct {
+	SchedulePlanID int64   `json:"schedulePlanID"`
+	Restricted     bool    `json:"restricted"`
+	Users          []*User `json:"users"`
+}
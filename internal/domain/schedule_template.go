@@ -4,19 +4,46 @@ import (
 	"time"
 )
 
+// ScheduleTemplateShiftDayRequirement 班次在某一天单独的人数要求
+type ScheduleTemplateShiftDayRequirement struct {
+	Day                     int32 `json:"day"`
+	RequiredAssistantNumber int32 `json:"requiredAssistantNumber"` // 包括负责人在内
+	PrincipalRequired       bool  `json:"principalRequired"`       // 为 false 时这一天不安排负责人，所有人都是助理
+}
+
 type ScheduleTemplateShift struct {
-	ID                      int64   `json:"id"`
-	StartTime               string  `json:"startTime"`
-	EndTime                 string  `json:"endTime"`
-	RequiredAssistantNumber int32   `json:"requiredAssistantNumber"`
-	ApplicableDays          []int32 `json:"applicableDays"`
+	ID                      int64                                 `json:"id"`
+	StartTime               string                                `json:"startTime"`
+	EndTime                 string                                `json:"endTime"`
+	Location                string                                `json:"location"` // 值班点，为空表示默认值班点
+	RequiredAssistantNumber int32                                 `json:"requiredAssistantNumber"`
+	ApplicableDays          []int32                               `json:"applicableDays"`
+	DayRequirements         []ScheduleTemplateShiftDayRequirement `json:"dayRequirements"` // 没有出现的天数沿用 RequiredAssistantNumber 并且需要负责人
+}
+
+// RequirementOn 返回班次在某一天需要的人数（包括负责人）以及是否需要负责人
+func (s *ScheduleTemplateShift) RequirementOn(day int32) (int32, bool) {
+	for _, requirement := range s.DayRequirements {
+		if requirement.Day == day {
+			return requirement.RequiredAssistantNumber, requirement.PrincipalRequired
+		}
+	}
+	return s.RequiredAssistantNumber, true
 }
 
+// ScheduleTemplate 排班模板
+// 排班计划在创建时会冻结一份模板的快照，快照不能被修改或删除，提交和排班结果都引用快照中的班次
+// 模板可以按照 RotationWeeks 周轮换（例如单双周），此时班次的适用天数、提交和排班结果中的天数都是周期内的天数，
+// 即 (第几周 - 1) * 7 + 星期几，只有一周时就是星期几本身
 type ScheduleTemplate struct {
-	ID          int64                   `json:"id"`
-	Name        string                  `json:"name"`
-	Description string                  `json:"description"`
-	Shifts      []ScheduleTemplateShift `json:"shifts"`
-	CreatedAt   time.Time               `json:"createdAt"`
-	Version     int32                   `json:"-"`
+	ID               int64                   `json:"id"`
+	Name             string                  `json:"name"`
+	Description      string                  `json:"description"`
+	RotationWeeks    int32                   `json:"rotationWeeks"`
+	Shifts           []ScheduleTemplateShift `json:"shifts"`
+	IsSnapshot       bool                    `json:"isSnapshot"`
+	SourceTemplateID *int64                  `json:"sourceTemplateID"` // 快照的源模板，源模板被删除后为 null
+	SourceVersion    *int32                  `json:"sourceVersion"`    // 创建快照时源模板的版本
+	CreatedAt        time.Time               `json:"createdAt"`
+	Version          int32                   `json:"-"`
 }
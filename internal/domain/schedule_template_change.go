@@ -0,0 +1,49 @@
+package domain
+
+// ScheduleTemplateShiftChange 被修改的班次，Before 和 After 的 ID 相同
+type ScheduleTemplateShiftChange struct {
+	Before      ScheduleTemplateShift `json:"before"`
+	After       ScheduleTemplateShift `json:"after"`
+	AddedDays   []int32               `json:"addedDays"`
+	RemovedDays []int32               `json:"removedDays"`
+}
+
+// ScheduleTemplateChanges 修改模板班次前后的差异
+type ScheduleTemplateChanges struct {
+	Added    []ScheduleTemplateShift       `json:"added"`
+	Removed  []ScheduleTemplateShift       `json:"removed"`
+	Modified []ScheduleTemplateShiftChange `json:"modified"`
+}
+
+func (c *ScheduleTemplateChanges) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// ScheduleTemplateImpact 修改模板班次对某个使用该模板的排班计划的影响
+// “变动的班次”指被删除或者被修改的班次，“删除的时段”指被删除的班次的所有天数以及被修改的班次中不再开放的天数
+type ScheduleTemplateImpact struct {
+	SchedulePlanID         int64              `json:"schedulePlanID"`
+	SchedulePlanName       string             `json:"schedulePlanName"`
+	Status                 SchedulePlanStatus `json:"status"`
+	AffectedSubmissions    int32              `json:"affectedSubmissions"`    // 最新提交中选择了变动的班次的用户数
+	DroppedSubmissionSlots int32              `json:"droppedSubmissionSlots"` // 最新提交中落在删除的时段上、迁移后会被丢弃的 (用户, 班次, 天)
+	AffectedResultItems    int32              `json:"affectedResultItems"`    // 排班结果中变动的班次上已经安排了人的 (班次, 天)
+	DroppedResultItems     int32              `json:"droppedResultItems"`     // 其中落在删除的时段上、迁移后会被丢弃的
+	OverstaffedResultItems int32              `json:"overstaffedResultItems"` // 减少人数（包括按天的人数要求）后超出人数要求的
+	PendingShiftSwaps      int32              `json:"pendingShiftSwaps"`      // 落在删除的时段上、还没有处理完的换班申请
+	LeaveRequests          int32              `json:"leaveRequests"`          // 变动的班次上待审批或者已批准的请假申请
+	ShiftInstances         int32              `json:"shiftInstances"`         // 变动的班次上已经生成的具体值班
+	RemovedShiftAttendance int32              `json:"removedShiftAttendance"` // 被删除的班次上已经有考勤记录（签到、签退或者被黑心修正过）的具体值班，存在时不能删除这些班次
+	RemovedShiftLeaves     int32              `json:"removedShiftLeaves"`     // 被删除的班次上的请假申请（包括已经处理完的），存在时不能删除这些班次
+}
+
+// BlocksShiftRemoval 被删除的班次上是否有考勤或者请假记录，这些记录不能随着班次一起删除
+func (i *ScheduleTemplateImpact) BlocksShiftRemoval() bool {
+	return i.RemovedShiftAttendance > 0 || i.RemovedShiftLeaves > 0
+}
+
+// ScheduleTemplateEditPreview 修改模板班次前的预览
+type ScheduleTemplateEditPreview struct {
+	Changes *ScheduleTemplateChanges  `json:"changes"`
+	Impacts []*ScheduleTemplateImpact `json:"impacts"`
+}
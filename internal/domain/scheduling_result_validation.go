@@ -0,0 +1,44 @@
+package domain
+
+type SchedulingResultViolationSeverity string
+
+const (
+	SchedulingResultViolationSeverityError   SchedulingResultViolationSeverity = "错误" // 排班结果不能保存
+	SchedulingResultViolationSeverityWarning SchedulingResultViolationSeverity = "警告" // 可以保存，但是需要黑心留意，例如人手不足
+)
+
+// 排班结果校验中每一类问题的代码，方便前端定位和展示
+const (
+	SchedulingResultViolationUnknownShift        = "unknown_shift"        // 班次不属于排班计划的模板
+	SchedulingResultViolationDuplicateShift      = "duplicate_shift"      // 同一个班次出现了多次
+	SchedulingResultViolationMissingShift        = "missing_shift"        // 模板中的班次没有出现在排班结果中
+	SchedulingResultViolationMissingDay          = "missing_day"          // 班次适用的某一天没有排班
+	SchedulingResultViolationInapplicableDay     = "inapplicable_day"     // 班次在这一天不需要值班
+	SchedulingResultViolationOverstaffed         = "overstaffed"          // 安排的人数超过了要求
+	SchedulingResultViolationUnderstaffed        = "understaffed"         // 安排的人数少于要求
+	SchedulingResultViolationMissingPrincipal    = "missing_principal"    // 需要负责人但是没有安排
+	SchedulingResultViolationIneligiblePrincipal = "ineligible_principal" // 负责人的身份不能担任负责人
+	SchedulingResultViolationDuplicateAssistant  = "duplicate_assistant"  // 同一个人在同一个 (shift, day) 中出现了多次
+	SchedulingResultViolationNoSubmission        = "no_submission"        // 安排的人没有提交空闲时间
+	SchedulingResultViolationUnavailable         = "unavailable"          // 安排的人在这个 (shift, day) 没有空
+	SchedulingResultViolationOverlap             = "overlap"              // 同一个人被安排到了时间重叠的班次
+	SchedulingResultViolationNotParticipant      = "not_participant"      // 安排的人不在参与名单中
+)
+
+// SchedulingResultViolation 排班结果中的一个问题，ShiftID、Day、UserID 为空表示和它们无关
+type SchedulingResultViolation struct {
+	Code     string                            `json:"code"`
+	Severity SchedulingResultViolationSeverity `json:"severity"`
+	Message  string                            `json:"message"`
+	ShiftID  *int64                            `json:"shiftID"`
+	Day      *int32                            `json:"day"`
+	UserID   *int64                            `json:"userID"`
+}
+
+// SchedulingResultValidationReport 排班结果的完整校验报告，包含所有发现的问题
+type SchedulingResultValidationReport struct {
+	Valid        bool                         `json:"valid"` // 没有错误时为 true，只有警告也可以保存
+	ErrorCount   int                          `json:"errorCount"`
+	WarningCount int                          `json:"warningCount"`
+	Violations   []*SchedulingResultViolation `json:"violations"`
+}
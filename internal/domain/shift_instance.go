@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+type AttendanceStatus string
+
+const (
+	AttendanceStatusPending           AttendanceStatus = "待签到"
+	AttendanceStatusOnDuty            AttendanceStatus = "值班中"
+	AttendanceStatusNormal            AttendanceStatus = "正常"
+	AttendanceStatusLate              AttendanceStatus = "迟到"
+	AttendanceStatusEarlyLeave        AttendanceStatus = "早退"
+	AttendanceStatusLateAndEarlyLeave AttendanceStatus = "迟到且早退"
+	AttendanceStatusAbsent            AttendanceStatus = "缺勤"
+	AttendanceStatusMissingCheckOut   AttendanceStatus = "未签退"
+)
+
+// ShiftInstance 是排班结果在某个具体日期上展开后的一次值班，同时记录考勤情况
+type ShiftInstance struct {
+	ID             int64             `json:"id"`
+	SchedulePlanID int64             `json:"schedulePlanID"`
+	ShiftID        int64             `json:"shiftID"`
+	Date           time.Time         `json:"date"` // 只有日期部分有意义
+	UserID         int64             `json:"userID"`
+	IsPrincipal    bool              `json:"isPrincipal"`
+	StartAt        time.Time         `json:"startAt"`
+	EndAt          time.Time         `json:"endAt"`
+	CheckInAt      *time.Time        `json:"checkInAt"`
+	CheckOutAt     *time.Time        `json:"checkOutAt"`
+	Status         AttendanceStatus  `json:"status"`         // 不存储在数据库中，由签到签退时间计算得到
+	StatusOverride *AttendanceStatus `json:"statusOverride"` // 黑心手动修正的考勤状态
+	Note           string            `json:"note"`
+	CorrectedBy    *int64            `json:"correctedBy"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	UpdatedAt      time.Time         `json:"updatedAt"`
+	Version        int32             `json:"-"`
+}
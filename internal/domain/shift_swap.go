@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+type ShiftSwapType string
+
+const (
+	ShiftSwapTypeGive     ShiftSwapType = "转让" // 把自己的班直接让给对方
+	ShiftSwapTypeExchange ShiftSwapType = "交换" // 和对方互换各自的班
+)
+
+type ShiftSwapStatus string
+
+const (
+	ShiftSwapStatusPending          ShiftSwapStatus = "待接受"
+	ShiftSwapStatusAwaitingApproval ShiftSwapStatus = "待审批"
+	ShiftSwapStatusCompleted        ShiftSwapStatus = "已完成"
+	ShiftSwapStatusDeclined         ShiftSwapStatus = "已拒绝" // 被对方拒绝
+	ShiftSwapStatusRejected         ShiftSwapStatus = "已驳回" // 被黑心驳回
+	ShiftSwapStatusCancelled        ShiftSwapStatus = "已取消"
+)
+
+type ShiftSwap struct {
+	ID                  int64           `json:"id"`
+	SchedulePlanID      int64           `json:"schedulePlanID"`
+	Type                ShiftSwapType   `json:"type"`
+	ProposerID          int64           `json:"proposerID"`
+	CounterpartyID      int64           `json:"counterpartyID"`
+	ShiftID             int64           `json:"shiftID"`
+	Day                 int32           `json:"day"`
+	CounterpartyShiftID *int64          `json:"counterpartyShiftID"` // 只有交换时才有值
+	CounterpartyDay     *int32          `json:"counterpartyDay"`     // 只有交换时才有值
+	Reason              string          `json:"reason"`
+	Status              ShiftSwapStatus `json:"status"`
+	ReviewerID          *int64          `json:"reviewerID"`
+	CreatedAt           time.Time       `json:"createdAt"`
+	UpdatedAt           time.Time       `json:"updatedAt"`
+	Version             int32           `json:"-"`
+}
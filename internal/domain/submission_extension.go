@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// SubmissionExtension 黑心为某个用户单独延长的提交截止时间
+type SubmissionExtension struct {
+	ID             int64     `json:"id"`
+	SchedulePlanID int64     `json:"schedulePlanID"`
+	UserID         int64     `json:"userID"`
+	Deadline       time.Time `json:"deadline"`
+	Reason         string    `json:"reason"`
+	GrantedBy      *int64    `json:"grantedBy"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Version        int32     `json:"-"`
+}
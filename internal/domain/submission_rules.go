@@ -0,0 +1,26 @@
+package domain
+
+// SubmissionRoleRule 某个身份的用户提交空闲时间时需要满足的要求，为 0 表示不限制
+type SubmissionRoleRule struct {
+	Role              Role  `json:"role"`
+	MinSlots          int32 `json:"minSlots"`          // 至少提交多少个有空的 (shift, day)
+	MinPrincipalSlots int32 `json:"minPrincipalSlots"` // 其中至少多少个是需要负责人的 (shift, day)
+	MinDistinctDays   int32 `json:"minDistinctDays"`   // 至少在多少个不同的天有空
+}
+
+// SubmissionRules 排班计划的提交要求，只在助理自己提交空闲时间时检查
+type SubmissionRules struct {
+	SchedulePlanID   int64                `json:"schedulePlanID"`
+	RoleRules        []SubmissionRoleRule `json:"roleRules"`
+	RequiredShiftIDs []int64              `json:"requiredShiftIDs"` // 每个人都至少要在其中一天有空的班次
+}
+
+// RuleFor 返回 role 对应的要求，没有设置时返回 nil
+func (r *SubmissionRules) RuleFor(role Role) *SubmissionRoleRule {
+	for i := range r.RoleRules {
+		if r.RoleRules[i].Role == role {
+			return &r.RoleRules[i]
+		}
+	}
+	return nil
+}
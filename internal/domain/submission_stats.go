@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+type SubmissionStatsUser struct {
+	UserID      int64      `json:"userID"`
+	Username    string     `json:"username"`
+	FullName    string     `json:"fullName"`
+	Role        Role       `json:"role"`
+	SubmittedAt *time.Time `json:"submittedAt"` // 还没有提交时为 null
+}
+
+// SubmissionStatsSlot 某个 (shift, day) 的空闲人数统计
+type SubmissionStatsSlot struct {
+	ShiftID                 int64  `json:"shiftID"`
+	StartTime               string `json:"startTime"`
+	EndTime                 string `json:"endTime"`
+	Location                string `json:"location"`
+	Day                     int32  `json:"day"`
+	RequiredAssistantNumber int32  `json:"requiredAssistantNumber"` // 班次在这一天需要的人数
+	PrincipalRequired       bool   `json:"principalRequired"`       // 班次在这一天是否需要负责人
+	AvailableCount          int32  `json:"availableCount"`          // 参与名单中有空的在职用户数
+	AvailablePrincipalCount int32  `json:"availablePrincipalCount"` // 其中能够担任负责人的用户数
+	Understaffed            bool   `json:"understaffed"`            // 有空的人数少于需要的人数
+	PrincipalShortage       bool   `json:"principalShortage"`       // 能够担任负责人的人数过少
+}
+
+type SubmissionStatsRole struct {
+	Role      Role    `json:"role"`
+	Total     int32   `json:"total"`     // 参与名单中的在职用户数
+	Submitted int32   `json:"submitted"` // 其中已经提交的用户数
+	Rate      float64 `json:"rate"`
+}
+
+type SubmissionStats struct {
+	SchedulePlanID     int64                  `json:"schedulePlanID"`
+	Submitted          []*SubmissionStatsUser `json:"submitted"`
+	NotSubmitted       []*SubmissionStatsUser `json:"notSubmitted"`
+	Slots              []*SubmissionStatsSlot `json:"slots"`
+	PrincipalShortages []*SubmissionStatsSlot `json:"principalShortages"`
+	Roles              []*SubmissionStatsRole `json:"roles"`
+}
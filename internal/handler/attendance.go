@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/attendance"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/instance"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/lifecycle"
+)
+
+func (h *Handler) attendanceRules() attendance.Rules {
+	return attendance.RulesFromConfig(h.config)
+}
+
+// planCalendar 获取排班计划的日历，包括例外日期和模板的轮换周期
+func (h *Handler) planCalendar(schedulePlanID int64) (*instance.Calendar, error) {
+	plan, err := h.repository.GetSchedulePlanByID(schedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+	template, err := h.repository.GetScheduleTemplate(plan.ScheduleTemplateID)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := h.repository.GetCalendarExceptionsBySchedulePlanID(schedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+	return instance.NewCalendar(plan, template, exceptions), nil
+}
+
+// syncShiftInstances 根据当前的排班结果和请假情况重新生成排班计划的具体值班
+// 在排班结果、换班或者代班发生变化之后都需要调用
+func (h *Handler) syncShiftInstances(plan *domain.SchedulePlan) error {
+	// 只有发布之后的排班结果才会生成具体的值班，归档之后也不再变化
+	if !lifecycle.IsPublished(plan) {
+		if plan.Status == domain.SchedulePlanStatusArchived {
+			return nil
+		}
+		// 撤回发布之后清除还没有考勤记录的值班，避免还能对它们签到
+		return h.repository.SyncShiftInstances(plan.ID, nil)
+	}
+
+	template, err := h.repository.GetScheduleTemplate(plan.ScheduleTemplateID)
+	if err != nil {
+		return err
+	}
+
+	schedulingResult, err := h.repository.GetSchedulingResultBySchedulePlanID(plan.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// 没有排班结果时也就没有任何值班
+			return h.repository.SyncShiftInstances(plan.ID, nil)
+		}
+		return err
+	}
+
+	leaves, err := h.repository.GetLeaveRequestsBySchedulePlanID(plan.ID)
+	if err != nil {
+		return err
+	}
+
+	calendar, err := h.planCalendar(plan.ID)
+	if err != nil {
+		return err
+	}
+
+	instances, err := instance.Generate(plan, template, schedulingResult, leaves, calendar)
+	if err != nil {
+		return err
+	}
+
+	return h.repository.SyncShiftInstances(plan.ID, instances)
+}
+
+func (h *Handler) shiftInstance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+		instanceID, err := strconv.ParseInt(chi.URLParam(r, "instanceID"), 10, 64)
+		if err != nil {
+			h.errorResponse(w, r, "值班ID无效")
+			return
+		}
+
+		shiftInstance, err := h.repository.GetShiftInstanceByID(instanceID)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				h.errorResponse(w, r, "值班不存在")
+			default:
+				h.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if shiftInstance.SchedulePlanID != plan.ID {
+			h.errorResponse(w, r, "值班不存在")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ShiftInstanceCtx, shiftInstance)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *Handler) MaterializeShiftInstances(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	if err := h.syncShiftInstances(plan); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	instances, err := h.repository.GetShiftInstancesBySchedulePlanID(plan.ID, nil, nil)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	for _, shiftInstance := range instances {
+		shiftInstance.Status = attendance.Evaluate(shiftInstance, now, h.attendanceRules())
+	}
+
+	h.successResponse(w, r, "生成值班成功", instances)
+}
+
+func (h *Handler) GetShiftInstances(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	var date *time.Time
+	if s := r.URL.Query().Get("date"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			h.errorResponse(w, r, "日期格式错误，应为 YYYY-MM-DD")
+			return
+		}
+		date = &d
+	}
+
+	var userID *int64
+	if s := r.URL.Query().Get("userID"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			h.errorResponse(w, r, "用户ID无效")
+			return
+		}
+		userID = &id
+	}
+
+	// 黑心可以看到所有人的考勤，其他人只能看到自己的
+	if myInfo.Role != domain.RoleBlackCore {
+		userID = &myInfo.ID
+	}
+
+	instances, err := h.repository.GetShiftInstancesBySchedulePlanID(plan.ID, date, userID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	for _, shiftInstance := range instances {
+		shiftInstance.Status = attendance.Evaluate(shiftInstance, now, h.attendanceRules())
+	}
+
+	h.successResponse(w, r, "获取考勤记录成功", instances)
+}
+
+// updateShiftInstanceAttendance 保存考勤信息的修改，处理乐观锁冲突
+func (h *Handler) updateShiftInstanceAttendance(w http.ResponseWriter, r *http.Request, shiftInstance *domain.ShiftInstance, msg string) {
+	if err := h.repository.UpdateShiftInstanceAttendance(shiftInstance); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "考勤记录已被修改，请重试")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	shiftInstance.Status = attendance.Evaluate(shiftInstance, time.Now(), h.attendanceRules())
+	h.successResponse(w, r, msg, shiftInstance)
+}
+
+func (h *Handler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	shiftInstance := r.Context().Value(ShiftInstanceCtx).(*domain.ShiftInstance)
+
+	if shiftInstance.UserID != myInfo.ID {
+		h.errorResponse(w, r, "只能为自己的值班签到")
+		return
+	}
+
+	if err := attendance.CheckIn(shiftInstance, time.Now(), h.attendanceRules()); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	h.updateShiftInstanceAttendance(w, r, shiftInstance, "签到成功")
+}
+
+func (h *Handler) CheckOut(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	shiftInstance := r.Context().Value(ShiftInstanceCtx).(*domain.ShiftInstance)
+
+	if shiftInstance.UserID != myInfo.ID {
+		h.errorResponse(w, r, "只能为自己的值班签退")
+		return
+	}
+
+	if err := attendance.CheckOut(shiftInstance, time.Now(), h.attendanceRules()); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	h.updateShiftInstanceAttendance(w, r, shiftInstance, "签退成功")
+}
+
+func (h *Handler) CorrectAttendance(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	shiftInstance := r.Context().Value(ShiftInstanceCtx).(*domain.ShiftInstance)
+
+	var req struct {
+		CheckInAt   *time.Time `json:"checkInAt"`
+		CheckOutAt  *time.Time `json:"checkOutAt"`
+		Status      *string    `json:"status" validate:"omitnil,oneof=正常 迟到 早退 迟到且早退 缺勤 未签退"`
+		ResetStatus bool       `json:"resetStatus"` // 清除手动修正的状态，恢复为自动计算
+		Note        *string    `json:"note"`
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	if req.CheckInAt != nil {
+		shiftInstance.CheckInAt = req.CheckInAt
+	}
+	if req.CheckOutAt != nil {
+		shiftInstance.CheckOutAt = req.CheckOutAt
+	}
+	if shiftInstance.CheckInAt != nil && shiftInstance.CheckOutAt != nil && shiftInstance.CheckOutAt.Before(*shiftInstance.CheckInAt) {
+		h.errorResponse(w, r, "签退时间不能早于签到时间")
+		return
+	}
+	if req.Status != nil {
+		status := domain.AttendanceStatus(*req.Status)
+		shiftInstance.StatusOverride = &status
+	}
+	if req.ResetStatus {
+		shiftInstance.StatusOverride = nil
+	}
+	if req.Note != nil {
+		shiftInstance.Note = *req.Note
+	}
+	shiftInstance.CorrectedBy = &myInfo.ID
+
+	h.updateShiftInstanceAttendance(w, r, shiftInstance, "修正考勤记录成功")
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/instance"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
+)
+
+const maxCalendarDays = 92
+
+type calendarEntry struct {
+	InstanceID     int64     `json:"instanceID"`
+	SchedulePlanID int64     `json:"schedulePlanID"`
+	ShiftID        int64     `json:"shiftID"`
+	StartAt        time.Time `json:"startAt"`
+	EndAt          time.Time `json:"endAt"`
+	UserID         int64     `json:"userID"`
+	FullName       string    `json:"fullName"`
+	IsPrincipal    bool      `json:"isPrincipal"`
+}
+
+type calendarDay struct {
+	Date       string                      `json:"date"`
+	DayOfWeek  int32                       `json:"dayOfWeek"`
+	Exceptions []*domain.CalendarException `json:"exceptions"`
+	Entries    []calendarEntry             `json:"entries"`
+}
+
+// GetCalendar 按日期返回 [from, to] 之间所有人的具体值班，所有助理都可以查看
+// ?format=ics 时导出为 iCalendar 文件，方便导入到日历软件中
+func (h *Handler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "ics" {
+		h.errorResponse(w, r, "不支持的导出格式")
+		return
+	}
+
+	from := utils.DateOf(time.Now())
+	if s := r.URL.Query().Get("from"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			h.errorResponse(w, r, "开始日期格式错误，应为 YYYY-MM-DD")
+			return
+		}
+		from = d
+	}
+
+	to := from.AddDate(0, 0, 6)
+	if s := r.URL.Query().Get("to"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			h.errorResponse(w, r, "结束日期格式错误，应为 YYYY-MM-DD")
+			return
+		}
+		to = d
+	}
+
+	if to.Before(from) {
+		h.errorResponse(w, r, "结束日期不能早于开始日期")
+		return
+	}
+	if to.Sub(from) >= maxCalendarDays*24*time.Hour {
+		h.errorResponse(w, r, "查询范围不能超过 92 天")
+		return
+	}
+
+	var userID *int64
+	if s := r.URL.Query().Get("userID"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			h.errorResponse(w, r, "用户ID无效")
+			return
+		}
+		userID = &id
+	}
+
+	instances, err := h.repository.GetShiftInstancesByDateRange(from, to, userID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	exceptions, err := h.repository.GetCalendarExceptionsByDateRange(from, to)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	users, err := h.getUsersMap()
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	if format == "ics" {
+		filename := fmt.Sprintf("值班日历_%s_%s.ics", from.Format("2006-01-02"), to.Format("2006-01-02"))
+		h.writeFile(w, r, "text/calendar; charset=utf-8", filename, false, []byte(instance.ICS(instances, users)))
+		return
+	}
+
+	days := make([]*calendarDay, 0)
+	index := make(map[string]*calendarDay)
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		day := &calendarDay{
+			Date:       date.Format("2006-01-02"),
+			DayOfWeek:  utils.DayOfWeek(date),
+			Exceptions: make([]*domain.CalendarException, 0),
+			Entries:    make([]calendarEntry, 0),
+		}
+		days = append(days, day)
+		index[day.Date] = day
+	}
+
+	for _, exception := range exceptions {
+		if day, exists := index[exception.Date.Format("2006-01-02")]; exists {
+			day.Exceptions = append(day.Exceptions, exception)
+		}
+	}
+
+	for _, shiftInstance := range instances {
+		entry := calendarEntry{
+			InstanceID:     shiftInstance.ID,
+			SchedulePlanID: shiftInstance.SchedulePlanID,
+			ShiftID:        shiftInstance.ShiftID,
+			StartAt:        shiftInstance.StartAt,
+			EndAt:          shiftInstance.EndAt,
+			UserID:         shiftInstance.UserID,
+			IsPrincipal:    shiftInstance.IsPrincipal,
+		}
+		if user, exists := users[shiftInstance.UserID]; exists {
+			entry.FullName = user.FullName
+		}
+
+		if day, exists := index[shiftInstance.Date.Format("2006-01-02")]; exists {
+			day.Entries = append(day.Entries, entry)
+		}
+	}
+
+	h.successResponse(w, r, "获取日历成功", days)
+}
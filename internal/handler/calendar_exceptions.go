@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/importer"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
+)
+
+func (h *Handler) calendarException(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+		exceptionID, err := strconv.ParseInt(chi.URLParam(r, "exceptionID"), 10, 64)
+		if err != nil {
+			h.errorResponse(w, r, "例外日期ID无效")
+			return
+		}
+
+		exception, err := h.repository.GetCalendarExceptionByID(exceptionID)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				h.errorResponse(w, r, "例外日期不存在")
+			default:
+				h.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if exception.SchedulePlanID != plan.ID {
+			h.errorResponse(w, r, "例外日期不存在")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), CalendarExceptionCtx, exception)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// handleCalendarExceptionError 处理写入例外日期时的数据库错误
+func (h *Handler) handleCalendarExceptionError(w http.ResponseWriter, r *http.Request, err error) {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		h.errorResponse(w, r, "例外日期已被修改，请重试")
+	case errors.As(err, &pgErr):
+		switch pgErr.ConstraintName {
+		case "calendar_exceptions_schedule_plan_id_date_key":
+			h.errorResponse(w, r, "该日期已经设置过例外")
+		default:
+			h.internalServerError(w, r, err)
+		}
+	default:
+		h.internalServerError(w, r, err)
+	}
+}
+
+func (h *Handler) GetCalendarExceptions(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	exceptions, err := h.repository.GetCalendarExceptionsBySchedulePlanID(plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "获取例外日期成功", exceptions)
+}
+
+func (h *Handler) CreateCalendarException(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	var req struct {
+		Date      string `json:"date" validate:"required,datetime=2006-01-02"`
+		Type      string `json:"type" validate:"required,oneof=停班 调休"`
+		WorkAsDay *int32 `json:"workAsDay" validate:"required_if=Type 调休,omitempty,min=1,max=7"`
+		Note      string `json:"note"`
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	date, _ := time.Parse("2006-01-02", req.Date) // 格式已经由 validator 检查过
+	exception := &domain.CalendarException{
+		SchedulePlanID: plan.ID,
+		Date:           date,
+		Type:           domain.CalendarExceptionType(req.Type),
+		Note:           req.Note,
+	}
+	if exception.Type == domain.CalendarExceptionTypeWorkday {
+		exception.WorkAsDay = req.WorkAsDay
+	}
+
+	if err := utils.ValidateCalendarException(exception, plan); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	if err := h.repository.CreateCalendarException(exception); err != nil {
+		h.handleCalendarExceptionError(w, r, err)
+		return
+	}
+
+	if err := h.syncShiftInstances(plan); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "添加例外日期成功", exception)
+}
+
+func (h *Handler) UpdateCalendarException(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+	exception := r.Context().Value(CalendarExceptionCtx).(*domain.CalendarException)
+
+	var req struct {
+		Date      *string `json:"date" validate:"omitnil,datetime=2006-01-02"`
+		Type      *string `json:"type" validate:"omitnil,oneof=停班 调休"`
+		WorkAsDay *int32  `json:"workAsDay" validate:"omitnil,min=1,max=7"`
+		Note      *string `json:"note"`
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	if req.Date != nil {
+		exception.Date, _ = time.Parse("2006-01-02", *req.Date)
+	}
+	if req.Type != nil {
+		exception.Type = domain.CalendarExceptionType(*req.Type)
+	}
+	if req.WorkAsDay != nil {
+		exception.WorkAsDay = req.WorkAsDay
+	}
+	if exception.Type == domain.CalendarExceptionTypeClosed {
+		exception.WorkAsDay = nil
+	}
+	if req.Note != nil {
+		exception.Note = *req.Note
+	}
+
+	if err := utils.ValidateCalendarException(exception, plan); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	if err := h.repository.UpdateCalendarException(exception); err != nil {
+		h.handleCalendarExceptionError(w, r, err)
+		return
+	}
+
+	if err := h.syncShiftInstances(plan); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "更新例外日期成功", exception)
+}
+
+func (h *Handler) DeleteCalendarException(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+	exception := r.Context().Value(CalendarExceptionCtx).(*domain.CalendarException)
+
+	if err := h.repository.DeleteCalendarException(exception.ID); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	if err := h.syncShiftInstances(plan); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "删除例外日期成功", nil)
+}
+
+func (h *Handler) ImportCalendarExceptions(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+
+	var defaultWorkAsDay *int32
+	if s := r.URL.Query().Get("defaultWorkAsDay"); s != "" {
+		day, err := strconv.ParseInt(s, 10, 32)
+		if err != nil || day < 1 || day > 7 {
+			h.errorResponse(w, r, "默认星期必须为 1-7")
+			return
+		}
+		d := int32(day)
+		defaultWorkAsDay = &d
+	}
+
+	data, err := h.readUploadedFile(w, r, "file")
+	if err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	preview, err := importer.ParseCalendarICS(bytes.NewReader(data), plan, defaultWorkAsDay)
+	if err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	if dryRun {
+		h.successResponse(w, r, "解析 ICS 成功，以下为预览结果", preview)
+		return
+	}
+
+	// 只要有一行存在错误就不写入，避免只导入了一部分的情况
+	if preview.HasErrors() {
+		h.errorResponseWithData(w, r, fmt.Sprintf("有 %d 天存在错误，请修正后重新导入", preview.ErrorCount), preview)
+		return
+	}
+
+	exceptions := preview.Exceptions(plan.ID)
+	if err := h.repository.UpsertCalendarExceptions(exceptions); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	if err := h.syncShiftInstances(plan); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, fmt.Sprintf("成功导入 %d 个例外日期", len(exceptions)), preview)
+}
@@ -11,4 +11,9 @@ var (
 	ScheduleTemplateCtx              ContextKey = "scheduleTemplate"
 	SchedulePlanCtx                  ContextKey = "schedulePlan"
 	LatestSubmissionAvailablePlanCtx ContextKey = "latestSubmissionAvailablePlan"
+	ShiftSwapCtx                     ContextKey = "shiftSwap"
+	LeaveRequestCtx                  ContextKey = "leaveRequest"
+	ShiftInstanceCtx                 ContextKey = "shiftInstance"
+	CalendarExceptionCtx             ContextKey = "calendarException"
+	LateSubmissionCtx                ContextKey = "lateSubmission"
 )
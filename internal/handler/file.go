@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// readUploadedFile 读取 multipart/form-data 请求中名为 field 的文件
+func (h *Handler) readUploadedFile(w http.ResponseWriter, r *http.Request, field string) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.config.Upload.MaxSize)
+	if err := r.ParseMultipartForm(h.config.Upload.MaxSize); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, errors.New("上传的文件过大")
+		}
+		return nil, errors.New("无法解析上传的文件")
+	}
+
+	file, _, err := r.FormFile(field)
+	if err != nil {
+		return nil, errors.New("没有找到上传的文件")
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// writeFile 将渲染好的文件内容直接返回给客户端，而不是包装在 JSON 响应中
+// 文件名可能包含中文，因此使用 RFC 5987 的 filename* 形式
+func (h *Handler) writeFile(w http.ResponseWriter, r *http.Request, contentType string, filename string, inline bool, data []byte) {
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename*=UTF-8''%s", disposition, url.PathEscape(filename)))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(data); err != nil {
+		h.logInternalServerError(r, err)
+	}
+}
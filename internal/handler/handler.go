@@ -75,6 +75,10 @@ func (h *Handler) RegisterRoutes() {
 		r.Route("/users", func(r chi.Router) {
 			r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Post("/", h.CreateUser)
 			r.Get("/", h.GetAllUserInfo) // 所有助理应该都有权限获取其他人的个人信息
+			// 批量导入用户，?dryRun=true 时只校验不写入
+			r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Post("/import", h.ImportUsers)
+			// 导出所有用户，?format=csv|json
+			r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Get("/export", h.ExportUsers)
 			r.Route("/{id}", func(r chi.Router) {
 				r.Use(h.userInfo)
 				r.Get("/", h.GetUserInfo)
@@ -92,9 +96,19 @@ func (h *Handler) RegisterRoutes() {
 				r.Get("/", h.GetScheduleTemplate)
 				r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Patch("/", h.UpdateScheduleTemplate)
 				r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Delete("/", h.DeleteScheduleTemplate)
+				// ?mode=preview|migrate|version，已被排班计划使用时需要先查看影响再选择迁移或者创建新版本
+				r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Put("/shifts", h.EditScheduleTemplateShifts)
+				r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Post("/clone", h.CloneScheduleTemplate)
 			})
 		})
 
+		r.Get("/calendar", h.GetCalendar) // ?from=YYYY-MM-DD&to=YYYY-MM-DD&userID=&format=json|ics
+
+		r.Route("/reports", func(r chi.Router) {
+			r.Use(h.RequiredRole([]domain.Role{domain.RoleBlackCore}))
+			r.Get("/payroll", h.GetPayrollReport) // ?month=YYYY-MM&format=json|csv|xlsx
+		})
+
 		r.Route("/schedule-plans", func(r chi.Router) {
 			r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Post("/", h.CreateSchedulePlan)
 			r.Get("/", h.GetAllSchedulePlans)
@@ -103,19 +117,108 @@ func (h *Handler) RegisterRoutes() {
 				r.Get("/", h.GetSchedulePlanByID)
 				r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Patch("/", h.UpdateSchedulePlan)
 				r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Delete("/", h.DeleteSchedulePlan)
+				// 手动变更排班计划的状态，只允许状态机中合法的变更
+				r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Patch("/status", h.UpdateSchedulePlanStatus)
+				// 复制为下一个学期的排班计划，所有时间整体平移
+				r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Post("/duplicate", h.DuplicateSchedulePlan)
 				r.Route("/your-submission", func(r chi.Router) {
 					r.Use(h.myInfo)
 					r.Use(h.preventLeavedAssistant)
+					r.Use(h.requireSchedulePlanParticipant)
 					r.Use(h.preventSubmit2unavailableSchedulePlan)
 					r.Post("/", h.SubmitYourAvailability)
 					r.Get("/", h.GetYourAvailabilitySubmission)
+					r.Post("/copy-from/{previousPlanID}", h.CopyYourAvailabilitySubmission) // 只返回草稿，确认之后再提交
+				})
+				r.Route("/submission-rules", func(r chi.Router) {
+					r.Get("/", h.GetSubmissionRules)
+					r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Put("/", h.SetSubmissionRules) // 按身份设置最少的空闲班次、天数，以及必须提交的班次
+				})
+				r.Route("/participants", func(r chi.Router) {
+					r.Use(h.RequiredRole([]domain.Role{domain.RoleBlackCore}))
+					r.Get("/", h.GetSchedulePlanParticipants)
+					r.Put("/", h.SetSchedulePlanParticipants) // 按照身份筛选或者单独挑选用户，没有设置名单时所有在职用户都可以参与
+					r.Delete("/", h.DeleteSchedulePlanParticipants)
+				})
+				r.Route("/submissions", func(r chi.Router) {
+					r.Use(h.RequiredRole([]domain.Role{domain.RoleBlackCore})) // 只有黑心能够获取所有的提交情况，防止泄露信息
+					r.Get("/", h.GetSchedulePlanSubmissions)
+					r.Get("/stats", h.GetSchedulePlanSubmissionStats)  // ?minPrincipals= 每个班次至少需要多少个有空的负责人，默认为 1
+					r.Post("/import", h.ImportSchedulePlanSubmissions) // ?dryRun=true 时只预览不写入
+					r.Get("/extensions", h.GetSubmissionExtensions)
+					r.Route("/{id}", func(r chi.Router) {
+						r.Use(h.userInfo)
+						r.Use(h.myInfo)
+						r.Get("/history", h.GetSchedulePlanSubmissionHistory) // 每次提交的内容以及与上一次提交的差异
+						r.Put("/extension", h.GrantSubmissionExtension)       // 单独延长该用户的截止时间
+						r.Delete("/extension", h.RevokeSubmissionExtension)
+						r.Post("/approve-late", h.ApproveLateSubmission)
+						r.Post("/reject-late", h.RejectLateSubmission)
+					})
 				})
-				r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Get("/submissions", h.GetSchedulePlanSubmissions) // 只有黑心能够获取所有的提交情况，防止泄露信息
 				r.Route("/scheduling-result", func(r chi.Router) {
 					r.Use(h.RequiredRole([]domain.Role{domain.RoleBlackCore}))
-					r.Post("/", h.SubmitSchedulingResult)
+					r.Post("/", h.SubmitSchedulingResult)           // 存在错误时不保存，只有警告（例如人手不足）时仍然保存
+					r.Post("/validate", h.ValidateSchedulingResult) // 只校验不保存，返回所有的错误和警告
 					r.Get("/", h.GetSchedulingResult)
 					r.Post("/generate", h.GenerateSchedulingResult)
+					r.Get("/print", h.PrintSchedulingResult) // ?format=html|pdf
+				})
+				r.Route("/shift-swaps", func(r chi.Router) {
+					r.Use(h.myInfo)
+					r.With(h.preventLeavedAssistant, h.requirePublishedSchedulePlan).Post("/", h.CreateShiftSwap)
+					r.Get("/", h.GetShiftSwaps) // 黑心可以看到所有的换班申请，其他人只能看到和自己有关的
+					r.Route("/{swapID}", func(r chi.Router) {
+						r.Use(h.shiftSwap)
+						r.Get("/", h.GetShiftSwap)
+						r.With(h.requirePublishedSchedulePlan).Post("/accept", h.AcceptShiftSwap)
+						r.Post("/decline", h.DeclineShiftSwap)
+						r.Post("/cancel", h.CancelShiftSwap)
+						r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore}), h.requirePublishedSchedulePlan).Post("/approve", h.ApproveShiftSwap)
+						r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Post("/reject", h.RejectShiftSwap)
+					})
+				})
+				r.Route("/leave-requests", func(r chi.Router) {
+					r.Use(h.myInfo)
+					r.With(h.preventLeavedAssistant, h.requirePublishedSchedulePlan).Post("/", h.CreateLeaveRequest)
+					r.Get("/", h.GetLeaveRequests) // 黑心可以看到所有的请假申请，其他人只能看到和自己有关的
+					r.Route("/{leaveID}", func(r chi.Router) {
+						r.Use(h.leaveRequest)
+						r.Get("/", h.GetLeaveRequest)
+						r.Post("/cancel", h.CancelLeaveRequest)
+						r.Group(func(r chi.Router) {
+							r.Use(h.RequiredRole([]domain.Role{domain.RoleBlackCore}))
+							r.With(h.requirePublishedSchedulePlan).Post("/approve", h.ApproveLeaveRequest) // 批准后返回代班候选人
+							r.Post("/reject", h.RejectLeaveRequest)
+							r.Get("/candidates", h.GetSubstituteCandidates)
+							r.With(h.requirePublishedSchedulePlan).Put("/substitute", h.SetLeaveSubstitute)
+						})
+					})
+				})
+				r.Route("/calendar-exceptions", func(r chi.Router) {
+					r.Get("/", h.GetCalendarExceptions)
+					r.Group(func(r chi.Router) {
+						r.Use(h.RequiredRole([]domain.Role{domain.RoleBlackCore}))
+						r.Post("/", h.CreateCalendarException)
+						// 从节假日日历的 ICS 文件导入，?dryRun=true 时只预览不写入，?defaultWorkAsDay= 指定调休日默认按照星期几排班
+						r.Post("/import", h.ImportCalendarExceptions)
+						r.Route("/{exceptionID}", func(r chi.Router) {
+							r.Use(h.calendarException)
+							r.Patch("/", h.UpdateCalendarException)
+							r.Delete("/", h.DeleteCalendarException)
+						})
+					})
+				})
+				r.Route("/attendance", func(r chi.Router) {
+					r.Use(h.myInfo)
+					r.Get("/", h.GetShiftInstances) // ?date=&userID=，非黑心只能看到自己的
+					r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore}), h.requirePublishedSchedulePlan).Post("/materialize", h.MaterializeShiftInstances)
+					r.Route("/{instanceID}", func(r chi.Router) {
+						r.Use(h.shiftInstance)
+						r.Post("/check-in", h.CheckIn)
+						r.Post("/check-out", h.CheckOut)
+						r.With(h.RequiredRole([]domain.Role{domain.RoleBlackCore})).Patch("/", h.CorrectAttendance)
+					})
 				})
 			})
 		})
@@ -33,10 +33,15 @@ type Response struct {
 }
 
 func (h *Handler) errorResponse(w http.ResponseWriter, r *http.Request, msg string) {
+	h.errorResponseWithData(w, r, msg, nil)
+}
+
+// errorResponseWithData 用于失败时仍需要返回详细信息的场景，例如导入时每一行的错误
+func (h *Handler) errorResponseWithData(w http.ResponseWriter, r *http.Request, msg string, data any) {
 	h.writeJSON(w, r, http.StatusOK, Response{
 		Success: false,
 		Message: msg,
-		Data:    nil,
+		Data:    data,
 	})
 }
 
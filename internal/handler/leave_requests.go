@@ -0,0 +1,410 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
+)
+
+func (h *Handler) leaveRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+		leaveID, err := strconv.ParseInt(chi.URLParam(r, "leaveID"), 10, 64)
+		if err != nil {
+			h.errorResponse(w, r, "请假申请ID无效")
+			return
+		}
+
+		leave, err := h.repository.GetLeaveRequestByID(leaveID)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				h.errorResponse(w, r, "请假申请不存在")
+			default:
+				h.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if leave.SchedulePlanID != plan.ID {
+			h.errorResponse(w, r, "请假申请不存在")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), LeaveRequestCtx, leave)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *Handler) CreateLeaveRequest(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	var req struct {
+		ShiftID int64  `json:"shiftID" validate:"required"`
+		Date    string `json:"date" validate:"required,datetime=2006-01-02"`
+		Reason  string `json:"reason" validate:"required"`
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	date, _ := time.Parse("2006-01-02", req.Date) // 格式已经由 validator 检查过
+	if date.Before(utils.DateOf(time.Now())) {
+		h.errorResponse(w, r, "不能为已经过去的日期请假")
+		return
+	}
+	if date.Before(utils.DateOf(plan.ActiveStartTime)) || date.After(utils.DateOf(plan.ActiveEndTime)) {
+		h.errorResponse(w, r, "请假日期不在排班计划的生效期间内")
+		return
+	}
+
+	schedulingResult, err := h.repository.GetSchedulingResultBySchedulePlanID(plan.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "该排班计划还没有排班结果")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	calendar, err := h.planCalendar(plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	day, open := calendar.DayOf(date)
+	if !open {
+		h.errorResponse(w, r, "该日期停班，无需请假")
+		return
+	}
+
+	if !utils.IsScheduledIn(schedulingResult, req.ShiftID, day, myInfo.ID) {
+		h.errorResponse(w, r, "您在该日期没有该班次的值班安排")
+		return
+	}
+
+	leave := &domain.LeaveRequest{
+		SchedulePlanID: plan.ID,
+		UserID:         myInfo.ID,
+		ShiftID:        req.ShiftID,
+		Date:           date,
+		Reason:         req.Reason,
+	}
+
+	if err := h.repository.CreateLeaveRequest(leave); err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.As(err, &pgErr):
+			switch pgErr.ConstraintName {
+			case "leave_requests_active_key":
+				h.errorResponse(w, r, "您已经为该班次提交过请假申请")
+			default:
+				h.internalServerError(w, r, err)
+			}
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	h.successResponse(w, r, "提交请假申请成功", leave)
+}
+
+func (h *Handler) GetLeaveRequests(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	leaves, err := h.repository.GetLeaveRequestsBySchedulePlanID(plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	// 黑心可以看到所有的请假申请，其他人只能看到自己的或者自己代班的
+	if myInfo.Role != domain.RoleBlackCore {
+		filtered := make([]*domain.LeaveRequest, 0)
+		for _, leave := range leaves {
+			if leave.UserID == myInfo.ID || (leave.SubstituteID != nil && *leave.SubstituteID == myInfo.ID) {
+				filtered = append(filtered, leave)
+			}
+		}
+		leaves = filtered
+	}
+
+	h.successResponse(w, r, "获取请假申请成功", leaves)
+}
+
+func (h *Handler) GetLeaveRequest(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	leave := r.Context().Value(LeaveRequestCtx).(*domain.LeaveRequest)
+
+	isSubstitute := leave.SubstituteID != nil && *leave.SubstituteID == myInfo.ID
+	if myInfo.Role != domain.RoleBlackCore && leave.UserID != myInfo.ID && !isSubstitute {
+		h.errorResponse(w, r, "权限不足")
+		return
+	}
+
+	h.successResponse(w, r, "获取请假申请成功", leave)
+}
+
+// updateLeaveRequest 保存请假申请的修改，处理乐观锁冲突
+func (h *Handler) updateLeaveRequest(w http.ResponseWriter, r *http.Request, leave *domain.LeaveRequest) bool {
+	if err := h.repository.UpdateLeaveRequest(leave); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "请假申请已被修改，请重试")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return false
+	}
+	return true
+}
+
+func (h *Handler) CancelLeaveRequest(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	leave := r.Context().Value(LeaveRequestCtx).(*domain.LeaveRequest)
+
+	if leave.UserID != myInfo.ID {
+		h.errorResponse(w, r, "只有申请人才能取消请假申请")
+		return
+	}
+	if leave.Status != domain.LeaveRequestStatusPending {
+		h.errorResponse(w, r, fmt.Sprintf("请假申请%s，无法取消", leave.Status))
+		return
+	}
+
+	leave.Status = domain.LeaveRequestStatusCancelled
+	if !h.updateLeaveRequest(w, r, leave) {
+		return
+	}
+
+	h.successResponse(w, r, "取消请假申请成功", leave)
+}
+
+// rankSubstituteCandidates 获取请假申请的代班候选人
+func (h *Handler) rankSubstituteCandidates(leave *domain.LeaveRequest) ([]*domain.SubstituteCandidate, error) {
+	schedulingResult, err := h.repository.GetSchedulingResultBySchedulePlanID(leave.SchedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	submissions, err := h.repository.GetAllSubmissionsBySchedulePlanID(leave.SchedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := h.repository.GetAllUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	leaves, err := h.repository.GetLeaveRequestsBySchedulePlanID(leave.SchedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar, err := h.planCalendar(leave.SchedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+	day, _ := calendar.DayOf(leave.Date)
+
+	plan, err := h.repository.GetSchedulePlanByID(leave.SchedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+	template, err := h.repository.GetScheduleTemplate(plan.ScheduleTemplateID)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.RankSubstituteCandidates(leave, day, template, schedulingResult, submissions, users, leaves, calendar.DayOf), nil
+}
+
+func (h *Handler) ApproveLeaveRequest(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+	leave := r.Context().Value(LeaveRequestCtx).(*domain.LeaveRequest)
+
+	if leave.Status != domain.LeaveRequestStatusPending {
+		h.errorResponse(w, r, fmt.Sprintf("请假申请%s，无法批准", leave.Status))
+		return
+	}
+
+	leave.Status = domain.LeaveRequestStatusApproved
+	leave.ReviewerID = &myInfo.ID
+	if !h.updateLeaveRequest(w, r, leave) {
+		return
+	}
+
+	// 没有安排代班之前，请假人的这次值班不再生成
+	if err := h.syncShiftInstances(plan); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	// 批准后直接返回代班候选人，方便黑心马上安排代班
+	candidates, err := h.rankSubstituteCandidates(leave)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "批准请假申请成功", map[string]any{
+		"leaveRequest": leave,
+		"candidates":   candidates,
+	})
+}
+
+func (h *Handler) RejectLeaveRequest(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	leave := r.Context().Value(LeaveRequestCtx).(*domain.LeaveRequest)
+
+	if leave.Status != domain.LeaveRequestStatusPending {
+		h.errorResponse(w, r, fmt.Sprintf("请假申请%s，无法驳回", leave.Status))
+		return
+	}
+
+	leave.Status = domain.LeaveRequestStatusRejected
+	leave.ReviewerID = &myInfo.ID
+	if !h.updateLeaveRequest(w, r, leave) {
+		return
+	}
+
+	h.successResponse(w, r, "驳回请假申请成功", leave)
+}
+
+func (h *Handler) GetSubstituteCandidates(w http.ResponseWriter, r *http.Request) {
+	leave := r.Context().Value(LeaveRequestCtx).(*domain.LeaveRequest)
+
+	candidates, err := h.rankSubstituteCandidates(leave)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "获取代班候选人成功", candidates)
+}
+
+func (h *Handler) SetLeaveSubstitute(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+	leave := r.Context().Value(LeaveRequestCtx).(*domain.LeaveRequest)
+
+	var req struct {
+		SubstituteID int64 `json:"substituteID" validate:"required"`
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	if leave.Status != domain.LeaveRequestStatusApproved {
+		h.errorResponse(w, r, "只有已批准的请假申请才能安排代班")
+		return
+	}
+	if req.SubstituteID == leave.UserID {
+		h.errorResponse(w, r, "代班人不能是请假人自己")
+		return
+	}
+
+	substitute, err := h.repository.GetUserByID(req.SubstituteID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "代班人不存在")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+	if !substitute.IsActive {
+		h.errorResponse(w, r, fmt.Sprintf("%s 已离职", substitute.FullName))
+		return
+	}
+
+	schedulingResult, err := h.repository.GetSchedulingResultBySchedulePlanID(leave.SchedulePlanID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	calendar, err := h.planCalendar(plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	day, _ := calendar.DayOf(leave.Date)
+	if utils.IsScheduledIn(schedulingResult, leave.ShiftID, day, substitute.ID) {
+		h.errorResponse(w, r, fmt.Sprintf("%s 当天已经在该班次中", substitute.FullName))
+		return
+	}
+	if utils.IsPrincipalOf(schedulingResult, leave.ShiftID, day, leave.UserID) && substitute.Role != domain.RoleSeniorAssistant && substitute.Role != domain.RoleBlackCore {
+		h.errorResponse(w, r, fmt.Sprintf("请假人是该班次的负责人，而 %s 不具备担任负责人的资格", substitute.FullName))
+		return
+	}
+
+	leaves, err := h.repository.GetLeaveRequestsBySchedulePlanID(leave.SchedulePlanID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+	for _, l := range leaves {
+		if l.ID == leave.ID || l.Status != domain.LeaveRequestStatusApproved || l.SubstituteID == nil {
+			continue
+		}
+		if *l.SubstituteID == substitute.ID && l.ShiftID == leave.ShiftID && l.Date.Equal(leave.Date) {
+			h.errorResponse(w, r, fmt.Sprintf("%s 当天已经在该班次代班", substitute.FullName))
+			return
+		}
+	}
+
+	// 不能让代班人同时出现在两个时间重叠的班次中，包括其它值班点的班次和前一天跨越午夜的班次
+	template, err := h.repository.GetScheduleTemplate(plan.ScheduleTemplateID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+	if utils.SubstituteOverlapsDuty(leave, template, schedulingResult, leaves, substitute.ID, calendar.DayOf) {
+		h.errorResponse(w, r, fmt.Sprintf("%s 当天已有和该班次时间重叠的值班或者代班", substitute.FullName))
+		return
+	}
+
+	leave.SubstituteID = &substitute.ID
+	if !h.updateLeaveRequest(w, r, leave) {
+		return
+	}
+
+	if err := h.syncShiftInstances(plan); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "安排代班成功", leave)
+}
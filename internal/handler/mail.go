@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/mailqueue"
+)
+
+// publishMails 将邮件批量发送到消息队列中，由 mail worker 负责真正发送
+func (h *Handler) publishMails(mailMessages ...domain.MailMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(h.config.RabbitMQ.PublishTimeout)*time.Second)
+	defer cancel()
+
+	return mailqueue.Publish(ctx, h.mailChannel, mailMessages...)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/lifecycle"
 )
 
 type ResponseWriter struct {
@@ -237,17 +238,68 @@ func (h *Handler) preventLeavedAssistant(next http.Handler) http.Handler {
 	})
 }
 
-func (h *Handler) preventSubmit2unavailableSchedulePlan(next http.Handler) http.Handler {
+// requireSchedulePlanParticipant 只允许排班计划参与名单中的用户继续操作
+func (h *Handler) requireSchedulePlanParticipant(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
 		plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
 
-		if plan.SubmissionStartTime.After(time.Now()) {
-			h.errorResponse(w, r, "暂未开放提交")
+		isParticipant, err := h.repository.IsSchedulePlanParticipant(plan.ID, myInfo.ID)
+		if err != nil {
+			h.internalServerError(w, r, err)
 			return
 		}
+		if !isParticipant {
+			h.errorResponse(w, r, "您不在该排班计划的参与名单中")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) preventSubmit2unavailableSchedulePlan(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+		switch plan.Status {
+		case domain.SchedulePlanStatusOpen:
+			next.ServeHTTP(w, r)
+		case domain.SchedulePlanStatusDraft:
+			h.errorResponse(w, r, "暂未开放提交")
+		case domain.SchedulePlanStatusClosed, domain.SchedulePlanStatusScheduling:
+			// 截止之后、发布之前，被单独延长了截止时间的用户仍然可以按时提交，其他人只能逾期提交
+			myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
 
-		if plan.SubmissionEndTime.Before(time.Now()) {
+			extension, err := h.repository.GetSubmissionExtension(plan.ID, myInfo.ID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				h.internalServerError(w, r, err)
+				return
+			}
+			if extension != nil && extension.Deadline.After(time.Now()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !h.config.LateSubmission.Enabled {
+				h.errorResponse(w, r, "已截止提交")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), LateSubmissionCtx, true)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		default:
 			h.errorResponse(w, r, "已截止提交")
+		}
+	})
+}
+
+func (h *Handler) requirePublishedSchedulePlan(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+		if !lifecycle.IsPublished(plan) {
+			h.errorResponse(w, r, fmt.Sprintf("排班计划当前状态为%s，排班结果未发布或已失效", plan.Status))
 			return
 		}
 
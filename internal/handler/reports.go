@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/payroll"
+)
+
+func (h *Handler) GetPayrollReport(w http.ResponseWriter, r *http.Request) {
+	month := time.Now()
+	if s := r.URL.Query().Get("month"); s != "" {
+		m, err := time.Parse("2006-01", s)
+		if err != nil {
+			h.errorResponse(w, r, "月份格式错误，应为 YYYY-MM")
+			return
+		}
+		month = m
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" && format != "xlsx" {
+		h.errorResponse(w, r, "不支持的导出格式")
+		return
+	}
+
+	report, err := payroll.Generate(h.repository, h.config, month)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	filename := fmt.Sprintf("工时工资表_%s", report.Month)
+
+	switch format {
+	case "csv":
+		var buf bytes.Buffer
+		if err := report.WriteCSV(&buf); err != nil {
+			h.internalServerError(w, r, err)
+			return
+		}
+		h.writeFile(w, r, "text/csv; charset=utf-8", filename+".csv", false, buf.Bytes())
+	case "xlsx":
+		var buf bytes.Buffer
+		if err := report.WriteXLSX(&buf); err != nil {
+			h.internalServerError(w, r, err)
+			return
+		}
+		h.writeFile(w, r, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", filename+".xlsx", false, buf.Bytes())
+	default:
+		h.successResponse(w, r, "获取工时报表成功", report)
+	}
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+func (h *Handler) GetSchedulePlanParticipants(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	participants, err := h.repository.GetSchedulePlanParticipants(plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "获取参与名单成功", participants)
+}
+
+// SetSchedulePlanParticipants 设置排班计划的参与名单
+// 名单由 roles 中所有身份的在职用户以及 userIDs 中单独挑选的用户组成，再去掉 excludeUserIDs 中的用户
+// 名单保存的是设置时的用户，之后新增的用户需要重新设置才会加入
+func (h *Handler) SetSchedulePlanParticipants(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	var req struct {
+		Roles          []domain.Role `json:"roles" validate:"dive,oneof=普通助理 资深助理 黑心"`
+		UserIDs        []int64       `json:"userIDs"`
+		ExcludeUserIDs []int64       `json:"excludeUserIDs"`
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	if plan.Status == domain.SchedulePlanStatusArchived {
+		h.errorResponse(w, r, "排班计划已归档，无法修改参与名单")
+		return
+	}
+
+	users, err := h.getUsersMap()
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	for _, userID := range req.UserIDs {
+		user, exists := users[userID]
+		if !exists {
+			h.errorResponse(w, r, fmt.Sprintf("id 为 %d 的用户不存在", userID))
+			return
+		}
+		if !user.IsActive {
+			h.errorResponse(w, r, fmt.Sprintf("%s 已离职，无法加入参与名单", user.FullName))
+			return
+		}
+	}
+
+	userIDs := make([]int64, 0)
+	for _, user := range users {
+		if !user.IsActive || slices.Contains(req.ExcludeUserIDs, user.ID) {
+			continue
+		}
+		if slices.Contains(req.Roles, user.Role) || slices.Contains(req.UserIDs, user.ID) {
+			userIDs = append(userIDs, user.ID)
+		}
+	}
+	slices.Sort(userIDs)
+
+	// 空的名单表示所有人都可以参与，为了避免误操作，取消名单必须显式调用 DELETE
+	if len(userIDs) == 0 {
+		h.errorResponse(w, r, "参与名单中没有任何在职用户，如需取消名单请直接删除")
+		return
+	}
+
+	if err := h.repository.SetSchedulePlanParticipants(plan.ID, userIDs); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	participants, err := h.repository.GetSchedulePlanParticipants(plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, fmt.Sprintf("参与名单已设置为 %d 人", len(participants.Users)), participants)
+}
+
+// DeleteSchedulePlanParticipants 取消排班计划的参与名单，之后所有在职用户都可以参与
+func (h *Handler) DeleteSchedulePlanParticipants(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	if plan.Status == domain.SchedulePlanStatusArchived {
+		h.errorResponse(w, r, "排班计划已归档，无法修改参与名单")
+		return
+	}
+
+	if err := h.repository.SetSchedulePlanParticipants(plan.ID, nil); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "已取消参与名单，所有在职用户都可以参与", nil)
+}
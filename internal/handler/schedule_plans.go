@@ -1,14 +1,22 @@
 package handler
 
 import (
+	"bytes"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/importer"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/lifecycle"
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/scheduler"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/timetable"
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
 )
 
@@ -21,6 +29,7 @@ func (h *Handler) CreateSchedulePlan(w http.ResponseWriter, r *http.Request) {
 		ActiveStartTime     time.Time `json:"activeStartTime" validate:"required"`
 		ActiveEndTime       time.Time `json:"activeEndTime" validate:"required"`
 		TemplateID          int64     `json:"templateID" validate:"required"`
+		RotationStartDate   string    `json:"rotationStartDate" validate:"omitempty,datetime=2006-01-02"` // 轮换模板第一周所在的日期
 	}
 
 	if err := h.readJSON(r, &req); err != nil {
@@ -41,6 +50,10 @@ func (h *Handler) CreateSchedulePlan(w http.ResponseWriter, r *http.Request) {
 		ActiveEndTime:       req.ActiveEndTime,
 		ScheduleTemplateID:  req.TemplateID,
 	}
+	if req.RotationStartDate != "" {
+		date, _ := time.Parse("2006-01-02", req.RotationStartDate) // 格式已经由 validator 检查过
+		plan.RotationStartDate = &date
+	}
 
 	// 检查 plan 的时间是否合法
 	if err := utils.ValidateSchedulePlanTime(plan); err != nil {
@@ -61,6 +74,8 @@ func (h *Handler) CreateSchedulePlan(w http.ResponseWriter, r *http.Request) {
 			default:
 				h.internalServerError(w, r, err)
 			}
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "排班计划模板不存在")
 		default:
 			h.internalServerError(w, r, err)
 		}
@@ -70,6 +85,82 @@ func (h *Handler) CreateSchedulePlan(w http.ResponseWriter, r *http.Request) {
 	h.successResponse(w, r, "创建排班计划成功", plan)
 }
 
+// DuplicateSchedulePlan 以现有排班计划为基础创建下一个学期的排班计划，所有时间整体平移 offsetDays 天
+// 不指定 templateID 时沿用原排班计划的模板快照，否则使用指定模板的新快照
+func (h *Handler) DuplicateSchedulePlan(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	var req struct {
+		Name             string  `json:"name" validate:"required"`
+		Description      *string `json:"description"`
+		OffsetDays       int     `json:"offsetDays" validate:"required"`
+		TemplateID       *int64  `json:"templateID"`
+		CopyParticipants bool    `json:"copyParticipants"` // 是否沿用原排班计划的参与名单
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	// 按照日历日平移，这样每个时间点在当地的钟点保持不变
+	duplicate := &domain.SchedulePlan{
+		Name:                req.Name,
+		Description:         plan.Description,
+		SubmissionStartTime: plan.SubmissionStartTime.AddDate(0, 0, req.OffsetDays),
+		SubmissionEndTime:   plan.SubmissionEndTime.AddDate(0, 0, req.OffsetDays),
+		ActiveStartTime:     plan.ActiveStartTime.AddDate(0, 0, req.OffsetDays),
+		ActiveEndTime:       plan.ActiveEndTime.AddDate(0, 0, req.OffsetDays),
+		ScheduleTemplateID:  plan.ScheduleTemplateID,
+	}
+	if plan.RotationStartDate != nil {
+		rotationStartDate := plan.RotationStartDate.AddDate(0, 0, req.OffsetDays)
+		duplicate.RotationStartDate = &rotationStartDate
+	}
+	if req.Description != nil {
+		duplicate.Description = *req.Description
+	}
+	if req.TemplateID != nil {
+		duplicate.ScheduleTemplateID = *req.TemplateID
+	}
+
+	if err := utils.ValidateSchedulePlanTime(duplicate); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	// 提交要求和排班计划的时间一样沿用原来的设置
+	unmapped, err := h.repository.DuplicateSchedulePlan(plan.ID, duplicate, req.CopyParticipants)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.As(err, &pgErr):
+			switch pgErr.ConstraintName {
+			case "schedule_plans_name_key":
+				h.errorResponse(w, r, "排班计划名称已存在")
+			default:
+				h.internalServerError(w, r, err)
+			}
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "排班计划模板不存在")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if unmapped > 0 {
+		h.successResponse(w, r, fmt.Sprintf("复制排班计划成功，但有 %d 个必须提交的班次在新模板中没有时间、值班点和天数都相同的班次，已忽略", unmapped), duplicate)
+		return
+	}
+
+	h.successResponse(w, r, "复制排班计划成功", duplicate)
+}
+
 func (h *Handler) GetSchedulePlanByID(w http.ResponseWriter, r *http.Request) {
 	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
 
@@ -97,6 +188,7 @@ func (h *Handler) UpdateSchedulePlan(w http.ResponseWriter, r *http.Request) {
 		SubmissionEndTime   *time.Time `json:"submissionEndTime"`
 		ActiveStartTime     *time.Time `json:"activeStartTime"`
 		ActiveEndTime       *time.Time `json:"activeEndTime"`
+		RotationStartDate   *string    `json:"rotationStartDate" validate:"omitnil,datetime=2006-01-02"`
 	}
 
 	if err := h.readJSON(r, &req); err != nil {
@@ -108,6 +200,8 @@ func (h *Handler) UpdateSchedulePlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := *plan
+
 	// 将输入的参数解析到 plan 中
 	if req.Name != nil {
 		plan.Name = *req.Name
@@ -127,12 +221,26 @@ func (h *Handler) UpdateSchedulePlan(w http.ResponseWriter, r *http.Request) {
 	if req.ActiveEndTime != nil {
 		plan.ActiveEndTime = *req.ActiveEndTime
 	}
+	if req.RotationStartDate != nil {
+		date, _ := time.Parse("2006-01-02", *req.RotationStartDate)
+		plan.RotationStartDate = &date
+	}
 
 	// 检查 plan 的时间是否合法
 	if err := utils.ValidateSchedulePlanTime(plan); err != nil {
 		h.badRequest(w, r, err)
 		return
 	}
+	// 再检查当前状态下是否允许修改这些时间
+	if err := lifecycle.ValidateEdit(&before, plan); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	// 修改提交时间之后需要重新确定提交阶段的状态，自动推进只会向前推进，无法重新开放已经截止的提交
+	if !before.SubmissionStartTime.Equal(plan.SubmissionStartTime) || !before.SubmissionEndTime.Equal(plan.SubmissionEndTime) {
+		plan.Status = lifecycle.DeriveStatus(plan, time.Now())
+	}
 
 	// 更新排班计划
 	if err := h.repository.UpdateSchedulePlan(plan); err != nil {
@@ -145,31 +253,114 @@ func (h *Handler) UpdateSchedulePlan(w http.ResponseWriter, r *http.Request) {
 			default:
 				h.internalServerError(w, r, err)
 			}
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "排班计划已被修改，请重试")
 		default:
 			h.internalServerError(w, r, err)
 		}
 		return
 	}
 
+	// 已经发布的排班计划的生效日期或者轮换周期变化之后，需要重新生成具体的值班
+	activeChanged := !before.ActiveStartTime.Equal(plan.ActiveStartTime) || !before.ActiveEndTime.Equal(plan.ActiveEndTime)
+	rotationChanged := (before.RotationStartDate == nil) != (plan.RotationStartDate == nil) ||
+		(before.RotationStartDate != nil && !before.RotationStartDate.Equal(*plan.RotationStartDate))
+	if lifecycle.IsPublished(plan) && (activeChanged || rotationChanged) {
+		if err := h.syncShiftInstances(plan); err != nil {
+			h.internalServerError(w, r, err)
+			return
+		}
+	}
+
 	h.successResponse(w, r, "更新排班计划成功", plan)
 }
 
 func (h *Handler) GetAllSchedulePlans(w http.ResponseWriter, r *http.Request) {
+	status := domain.SchedulePlanStatus(r.URL.Query().Get("status"))
+	if status != "" && !lifecycle.IsValidStatus(status) {
+		h.errorResponse(w, r, "无效的排班计划状态")
+		return
+	}
+
 	plans, err := h.repository.GetAllSchedulePlans()
 	if err != nil {
 		h.internalServerError(w, r, err)
+		return
+	}
+
+	if status != "" {
+		filtered := make([]*domain.SchedulePlan, 0)
+		for _, plan := range plans {
+			if plan.Status == status {
+				filtered = append(filtered, plan)
+			}
+		}
+		plans = filtered
 	}
 
 	h.successResponse(w, r, "获取所有排班计划成功", plans)
 }
 
+func (h *Handler) UpdateSchedulePlanStatus(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	var req struct {
+		Status string `json:"status" validate:"required,oneof=草稿 收集中 已截止 排班中 已发布 生效中 已归档"`
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	hasResult := true
+	if _, err := h.repository.GetSchedulingResultBySchedulePlanID(plan.ID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			hasResult = false
+		default:
+			h.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	to := domain.SchedulePlanStatus(req.Status)
+	if err := lifecycle.ValidateTransition(plan, to, hasResult, time.Now()); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	plan.Status = to
+	if err := h.repository.UpdateSchedulePlanStatus(plan); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "排班计划已被修改，请重试")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// 发布之后根据排班结果生成具体的值班，撤回发布之后清除还没有考勤记录的值班
+	if err := h.syncShiftInstances(plan); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, fmt.Sprintf("排班计划状态已变更为%s", to), plan)
+}
+
 func (h *Handler) SubmitYourAvailability(w http.ResponseWriter, r *http.Request) {
 	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
 	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
 
 	var req []struct {
 		ShiftID int64   `json:"shiftID" validate:"required"`
-		Days    []int32 `json:"days" validate:"required,dive,min=1,max=7"`
+		Days    []int32 `json:"days" validate:"required,dive,min=1"`
 	}
 
 	if err := h.readJSON(r, &req); err != nil {
@@ -207,14 +398,95 @@ func (h *Handler) SubmitYourAvailability(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// 再检查是否满足排班计划对自己身份的提交要求
+	rules, err := h.repository.GetSubmissionRules(plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+	if err := utils.ValidateSubmissionRules(submission, template, rules, myInfo.Role); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	late, _ := r.Context().Value(LateSubmissionCtx).(bool)
+	if late {
+		status := domain.LateSubmissionStatusPending
+		submission.LateStatus = &status
+	}
+
 	if err := h.repository.InsertAvailabilitySubmission(submission); err != nil {
 		h.internalServerError(w, r, err)
 		return
 	}
 
+	if late {
+		h.successResponse(w, r, "已逾期提交空闲时间，需要等待黑心审批", submission)
+		return
+	}
+
 	h.successResponse(w, r, "成功提交空闲时间", submission)
 }
 
+// CopyYourAvailabilitySubmission 根据之前排班计划中的提交生成一份草稿，不会直接提交
+func (h *Handler) CopyYourAvailabilitySubmission(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	previousPlanID, err := strconv.ParseInt(chi.URLParam(r, "previousPlanID"), 10, 64)
+	if err != nil {
+		h.errorResponse(w, r, "无效的排班计划 ID")
+		return
+	}
+	if previousPlanID == plan.ID {
+		h.errorResponse(w, r, "不能从同一个排班计划中复制")
+		return
+	}
+
+	previousPlan, err := h.repository.GetSchedulePlanByID(previousPlanID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "之前的排班计划不存在")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	submission, err := h.repository.GetAvailabilitySubmissionByUserIDAndSchedulePlanID(myInfo.ID, previousPlan.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, fmt.Sprintf("你在排班计划 %s 中没有提交过空闲时间", previousPlan.Name))
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	from, err := h.repository.GetScheduleTemplate(previousPlan.ScheduleTemplateID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+	to, err := h.repository.GetScheduleTemplate(plan.ScheduleTemplateID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	items, unmapped := utils.MapSubmissionToTemplate(submission, from, to)
+	draft := &domain.AvailabilitySubmissionDraft{
+		SchedulePlanID:       plan.ID,
+		SourceSchedulePlanID: previousPlan.ID,
+		Items:                items,
+		Unmapped:             unmapped,
+	}
+
+	h.successResponse(w, r, "已根据之前的提交生成草稿，请确认后提交", draft)
+}
+
 func (h *Handler) GetYourAvailabilitySubmission(w http.ResponseWriter, r *http.Request) {
 	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
 	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
@@ -236,22 +508,144 @@ func (h *Handler) GetYourAvailabilitySubmission(w http.ResponseWriter, r *http.R
 func (h *Handler) GetSchedulePlanSubmissions(w http.ResponseWriter, r *http.Request) {
 	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
 
-	submissions, err := h.repository.GetAllSubmissionsBySchedulePlanID(plan.ID)
+	// 列表中包含还没有审批的逾期提交，以及每个用户被延长的截止时间
+	submissions, err := h.repository.GetLatestSubmissionsBySchedulePlanID(plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	extensions, err := h.repository.GetSubmissionExtensionsBySchedulePlanID(plan.ID)
 	if err != nil {
 		h.internalServerError(w, r, err)
 		return
 	}
 
+	extensionsMap := make(map[int64]*domain.SubmissionExtension)
+	for _, extension := range extensions {
+		extensionsMap[extension.UserID] = extension
+	}
+	for _, submission := range submissions {
+		submission.Extension = extensionsMap[submission.UserID]
+	}
+
 	h.successResponse(w, r, "获取该排班计划所有的提交记录成功", submissions)
 }
 
-func (h *Handler) SubmitSchedulingResult(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetSchedulePlanSubmissionHistory(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(UserInfoCtx).(*domain.User)
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	submissions, err := h.repository.GetAvailabilitySubmissionHistory(user.ID, plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	revisions := make([]*domain.AvailabilitySubmissionRevision, 0, len(submissions))
+	var prev *domain.AvailabilitySubmission = nil
+	for _, submission := range submissions {
+		revisions = append(revisions, &domain.AvailabilitySubmissionRevision{
+			Submission: submission,
+			Diff:       utils.DiffAvailabilitySubmissions(prev, submission),
+		})
+		prev = submission
+	}
+
+	h.successResponse(w, r, "获取提交历史成功", revisions)
+}
+
+func (h *Handler) GetSchedulePlanSubmissionStats(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	// 每个班次默认至少需要一个有空的负责人
+	minPrincipals := 1
+	if v := r.URL.Query().Get("minPrincipals"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			h.errorResponse(w, r, "minPrincipals 必须是非负整数")
+			return
+		}
+		minPrincipals = n
+	}
+
+	stats, err := h.repository.GetSubmissionStats(plan, minPrincipals)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "获取提交情况统计成功", stats)
+}
+
+func (h *Handler) ImportSchedulePlanSubmissions(w http.ResponseWriter, r *http.Request) {
 	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+
+	data, err := h.readUploadedFile(w, r, "file")
+	if err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
 
+	template, err := h.repository.GetScheduleTemplate(plan.ScheduleTemplateID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	users, err := h.repository.GetAllUsers()
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	// 只能导入参与名单中的用户的提交
+	participants, err := h.repository.GetSchedulePlanParticipants(plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	preview, err := importer.ParseAvailabilityCSV(bytes.NewReader(data), template, users, participants.Users)
+	if err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	if dryRun {
+		h.successResponse(w, r, "解析 CSV 成功，以下为预览结果", preview)
+		return
+	}
+
+	// 只要有一行存在错误就不写入，避免只导入了一部分的情况
+	if preview.HasErrors() {
+		h.errorResponseWithData(w, r, fmt.Sprintf("有 %d 行存在错误，请修正后重新导入", preview.ErrorCount), preview)
+		return
+	}
+
+	submissions := preview.Submissions(plan.ID)
+	for _, submission := range submissions {
+		if err := utils.ValidateSubmissionWithTemplate(submission, template); err != nil {
+			h.badRequest(w, r, err)
+			return
+		}
+	}
+
+	if err := h.repository.InsertAvailabilitySubmissions(submissions); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, fmt.Sprintf("成功导入 %d 条提交记录", len(submissions)), preview)
+}
+
+// readSchedulingResult 从请求中解析排班结果，解析失败时已经写好了响应
+func (h *Handler) readSchedulingResult(w http.ResponseWriter, r *http.Request, plan *domain.SchedulePlan) (*domain.SchedulingResult, bool) {
 	var req []struct {
 		ShiftID int64 `json:"shiftID" validate:"required"`
 		Items   []struct {
-			Day          int32   `json:"day" validate:"required,min=1,max=7"`
+			Day          int32   `json:"day" validate:"required,min=1"`
 			PrincipalID  *int64  `json:"principalID"`
 			AssistantIDs []int64 `json:"assistantIDs" validate:"required"`
 		} `json:"items" validate:"required,dive"`
@@ -259,11 +653,11 @@ func (h *Handler) SubmitSchedulingResult(w http.ResponseWriter, r *http.Request)
 
 	if err := h.readJSON(r, &req); err != nil {
 		h.badRequest(w, r, err)
-		return
+		return nil, false
 	}
 	if err := h.validate.Var(req, "required,dive"); err != nil {
 		h.badRequest(w, r, err)
-		return
+		return nil, false
 	}
 
 	schedulingResult := &domain.SchedulingResult{
@@ -286,40 +680,94 @@ func (h *Handler) SubmitSchedulingResult(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	// 必须检查提交的结果是否和模板对的上
+	return schedulingResult, true
+}
+
+// validateSchedulingResult 对照模板、助理的提交、用户身份和参与名单对排班结果做完整的校验
+func (h *Handler) validateSchedulingResult(plan *domain.SchedulePlan, schedulingResult *domain.SchedulingResult) (*domain.SchedulingResultValidationReport, error) {
 	template, err := h.repository.GetScheduleTemplate(plan.ScheduleTemplateID)
 	if err != nil {
-		h.internalServerError(w, r, err)
-		return
+		return nil, err
 	}
 
-	if err := utils.ValidateSchedulingResultWithTemplate(schedulingResult, template); err != nil {
-		h.badRequest(w, r, err)
+	submissions, err := h.repository.GetAllSubmissionsBySchedulePlanID(plan.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := h.getUsersMap()
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := h.repository.GetSchedulePlanParticipants(plan.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.ValidateSchedulingResult(schedulingResult, template, submissions, users, participants), nil
+}
+
+// ValidateSchedulingResult 只校验排班结果而不保存，返回所有发现的错误和警告
+func (h *Handler) ValidateSchedulingResult(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	schedulingResult, ok := h.readSchedulingResult(w, r, plan)
+	if !ok {
 		return
 	}
 
-	// 还要检查提交的结果是否和助理提交的结果对的上
-	submissions, err := h.repository.GetAllSubmissionsBySchedulePlanID(plan.ID)
+	report, err := h.validateSchedulingResult(plan, schedulingResult)
 	if err != nil {
 		h.internalServerError(w, r, err)
 		return
 	}
 
-	if err := utils.ValidateSchedulingResultWithSubmissions(schedulingResult, submissions); err != nil {
-		h.badRequest(w, r, err)
+	h.successResponse(w, r, fmt.Sprintf("校验完成，共有 %d 个错误和 %d 个警告", report.ErrorCount, report.WarningCount), report)
+}
+
+// SubmitSchedulingResult 保存排班结果，存在错误时不保存并返回完整的校验报告，只有警告时仍然保存
+func (h *Handler) SubmitSchedulingResult(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	if !lifecycle.AllowSchedulingResultChange(plan) {
+		h.errorResponse(w, r, fmt.Sprintf("排班计划当前状态为%s，无法修改排班结果", plan.Status))
 		return
 	}
 
-	// 最后要检查是否存在重复的助理
-	if err := utils.ValidIfExistsDuplicateAssistant(schedulingResult); err != nil {
-		h.badRequest(w, r, err)
+	schedulingResult, ok := h.readSchedulingResult(w, r, plan)
+	if !ok {
 		return
 	}
 
-	if err := h.repository.InsertSchedulingResult(schedulingResult); err != nil {
+	report, err := h.validateSchedulingResult(plan, schedulingResult)
+	if err != nil {
 		h.internalServerError(w, r, err)
 		return
 	}
+	if !report.Valid {
+		h.errorResponseWithData(w, r, fmt.Sprintf("排班结果存在 %d 个错误，请修正后重新提交", report.ErrorCount), report)
+		return
+	}
+
+	// 第一次提交排班结果时排班计划会在同一个事务中进入排班中
+	if err := h.repository.InsertSchedulingResult(plan, schedulingResult); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "排班计划已被修改，请重试")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if report.WarningCount > 0 {
+		h.successResponse(w, r, fmt.Sprintf("提交排班结果成功，但存在 %d 个警告", report.WarningCount), map[string]any{
+			"schedulingResult": schedulingResult,
+			"report":           report,
+		})
+		return
+	}
 
 	h.successResponse(w, r, "提交排班结果成功", schedulingResult)
 }
@@ -387,15 +835,18 @@ func (h *Handler) GenerateSchedulingResult(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// 获取排班计划所用的用户
-	users, err := h.repository.GetAllUsers()
+	// 只有参与名单中的用户参与排班，不在名单中的用户的提交会被忽略
+	participants, err := h.repository.GetSchedulePlanParticipants(plan.ID)
 	if err != nil {
 		h.internalServerError(w, r, err)
 		return
 	}
+	submissions = slices.DeleteFunc(submissions, func(submission *domain.AvailabilitySubmission) bool {
+		return !slices.ContainsFunc(participants.Users, func(user *domain.User) bool { return user.ID == submission.UserID })
+	})
 
 	// 自动排班
-	scheduler, err := scheduler.New(parameters, users, template, submissions)
+	scheduler, err := scheduler.New(parameters, participants.Users, template, submissions)
 	if err != nil {
 		h.internalServerError(w, r, err)
 		return
@@ -409,3 +860,63 @@ func (h *Handler) GenerateSchedulingResult(w http.ResponseWriter, r *http.Reques
 
 	h.successResponse(w, r, "自动排班成功", res)
 }
+
+func (h *Handler) PrintSchedulingResult(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "html"
+	}
+	if format != "html" && format != "pdf" {
+		h.errorResponse(w, r, "不支持的导出格式")
+		return
+	}
+
+	schedulingResult, err := h.repository.GetSchedulingResultBySchedulePlanID(plan.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "该排班计划还没有排班结果")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	template, err := h.repository.GetScheduleTemplate(plan.ScheduleTemplateID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	users, err := h.repository.GetAllUsers()
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	tt := timetable.New(plan, template, schedulingResult, users)
+
+	// 先渲染到缓冲区中，这样渲染失败时仍然可以返回 JSON 格式的错误信息
+	var buf bytes.Buffer
+	switch format {
+	case "pdf":
+		if err := tt.RenderPDF(&buf, h.config.Print.FontPath); err != nil {
+			switch {
+			case errors.Is(err, timetable.ErrPDFFontNotConfigured):
+				h.errorResponse(w, r, err.Error())
+			default:
+				h.internalServerError(w, r, err)
+			}
+			return
+		}
+		h.writeFile(w, r, "application/pdf", plan.Name+".pdf", true, buf.Bytes())
+	default:
+		if err := tt.RenderHTML(&buf); err != nil {
+			h.internalServerError(w, r, err)
+			return
+		}
+		h.writeFile(w, r, "text/html; charset=utf-8", plan.Name+".html", true, buf.Bytes())
+	}
+}
@@ -3,13 +3,40 @@ package handler
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/repository"
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
 )
 
+// scheduleTemplateShiftDayRequirementRequest 请求中班次在某一天单独的人数要求，不填 principalRequired 时默认需要负责人
+type scheduleTemplateShiftDayRequirementRequest struct {
+	Day                     int32 `json:"day" validate:"gte=1"`
+	RequiredAssistantNumber int32 `json:"requiredAssistantNumber" validate:"required,gte=1"`
+	PrincipalRequired       *bool `json:"principalRequired"`
+}
+
+func toScheduleTemplateShiftDayRequirements(reqs []scheduleTemplateShiftDayRequirementRequest) []domain.ScheduleTemplateShiftDayRequirement {
+	requirements := make([]domain.ScheduleTemplateShiftDayRequirement, 0, len(reqs))
+	for _, req := range reqs {
+		requirement := domain.ScheduleTemplateShiftDayRequirement{
+			Day:                     req.Day,
+			RequiredAssistantNumber: req.RequiredAssistantNumber,
+			PrincipalRequired:       true,
+		}
+		if req.PrincipalRequired != nil {
+			requirement.PrincipalRequired = *req.PrincipalRequired
+		}
+		requirements = append(requirements, requirement)
+	}
+	return requirements
+}
+
 func (h *Handler) GetAllScheduleTemplates(w http.ResponseWriter, r *http.Request) {
 	sts, err := h.repository.GetAllScheduleTemplates()
 	if err != nil {
@@ -22,13 +49,16 @@ func (h *Handler) GetAllScheduleTemplates(w http.ResponseWriter, r *http.Request
 
 func (h *Handler) CreateScheduleTemplate(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Name        string `json:"name" validate:"required"`
-		Description string `json:"description"`
-		Shifts      []struct {
-			StartTime               string  `json:"startTime" validate:"required"`
-			EndTime                 string  `json:"endTime" validate:"required"`
-			RequiredAssistantNumber int32   `json:"requiredAssistantNumber" validate:"required,gte=1"`
-			ApplicableDays          []int32 `json:"applicableDays" validate:"required,dive,gte=1,lte=7"`
+		Name          string `json:"name" validate:"required"`
+		Description   string `json:"description"`
+		RotationWeeks int32  `json:"rotationWeeks" validate:"omitempty,gte=1,lte=4"` // 不填时不轮换，单双周轮换时为 2
+		Shifts        []struct {
+			StartTime               string                                       `json:"startTime" validate:"required"`
+			EndTime                 string                                       `json:"endTime" validate:"required"`
+			Location                string                                       `json:"location"`
+			RequiredAssistantNumber int32                                        `json:"requiredAssistantNumber" validate:"required,gte=1"`
+			ApplicableDays          []int32                                      `json:"applicableDays" validate:"required,dive,gte=1"`
+			DayRequirements         []scheduleTemplateShiftDayRequirementRequest `json:"dayRequirements" validate:"dive"`
 		} `json:"shifts" validate:"required,dive"`
 	}
 
@@ -42,17 +72,20 @@ func (h *Handler) CreateScheduleTemplate(w http.ResponseWriter, r *http.Request)
 	}
 
 	st := &domain.ScheduleTemplate{
-		Name:        req.Name,
-		Description: req.Description,
-		Shifts:      make([]domain.ScheduleTemplateShift, 0, len(req.Shifts)),
+		Name:          req.Name,
+		Description:   req.Description,
+		RotationWeeks: max(req.RotationWeeks, 1),
+		Shifts:        make([]domain.ScheduleTemplateShift, 0, len(req.Shifts)),
 	}
 
 	for _, shift := range req.Shifts {
 		st.Shifts = append(st.Shifts, domain.ScheduleTemplateShift{
 			StartTime:               shift.StartTime,
 			EndTime:                 shift.EndTime,
+			Location:                shift.Location,
 			RequiredAssistantNumber: shift.RequiredAssistantNumber,
 			ApplicableDays:          shift.ApplicableDays,
+			DayRequirements:         toScheduleTemplateShiftDayRequirements(shift.DayRequirements),
 		})
 	}
 
@@ -60,13 +93,17 @@ func (h *Handler) CreateScheduleTemplate(w http.ResponseWriter, r *http.Request)
 		h.badRequest(w, r, err)
 		return
 	}
+	if err := utils.ValidateScheduleTemplateShiftRequirements(st); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
 
 	if err := h.repository.CreateScheduleTemplate(st); err != nil {
 		var pgErr *pgconn.PgError
 		switch {
 		case errors.As(err, &pgErr):
 			switch pgErr.ConstraintName {
-			case "schedule_template_meta_name_key":
+			case "schedule_templates_name_key":
 				h.errorResponse(w, r, "模板名称已存在")
 			default:
 				h.internalServerError(w, r, err)
@@ -89,6 +126,11 @@ func (h *Handler) GetScheduleTemplate(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateScheduleTemplate(w http.ResponseWriter, r *http.Request) {
 	st := r.Context().Value(ScheduleTemplateCtx).(*domain.ScheduleTemplate)
 
+	if st.IsSnapshot {
+		h.errorResponse(w, r, "排班计划使用的模板快照无法修改")
+		return
+	}
+
 	var req struct {
 		Name        *string `json:"name"`
 		Description *string `json:"description"`
@@ -115,7 +157,7 @@ func (h *Handler) UpdateScheduleTemplate(w http.ResponseWriter, r *http.Request)
 		switch {
 		case errors.As(err, &pgErr):
 			switch pgErr.ConstraintName {
-			case "schedule_template_meta_name_key":
+			case "schedule_templates_name_key":
 				h.errorResponse(w, r, "模板名称已存在")
 			default:
 				h.internalServerError(w, r, err)
@@ -134,6 +176,11 @@ func (h *Handler) UpdateScheduleTemplate(w http.ResponseWriter, r *http.Request)
 func (h *Handler) DeleteScheduleTemplate(w http.ResponseWriter, r *http.Request) {
 	st := r.Context().Value(ScheduleTemplateCtx).(*domain.ScheduleTemplate)
 
+	if st.IsSnapshot {
+		h.errorResponse(w, r, "排班计划使用的模板快照无法删除")
+		return
+	}
+
 	if err := h.repository.DeleteScheduleTemplate(st.ID); err != nil {
 		var pgErr *pgconn.PgError
 		switch {
@@ -152,3 +199,311 @@ func (h *Handler) DeleteScheduleTemplate(w http.ResponseWriter, r *http.Request)
 
 	h.successResponse(w, r, "删除模板成功", nil)
 }
+
+// EditScheduleTemplateShifts 修改模板的班次，请求中带 id 的班次表示修改，不带 id 的表示新增，没有出现的表示删除
+// ?mode=preview 只返回修改前后的差异和影响分析；
+// ?mode=migrate 原地修改模板，并迁移所有使用该模板的排班计划中依赖这些班次的数据；
+// ?mode=version 不修改原模板，以修改后的班次创建一个新模板，已有的排班计划不受影响；
+// 不指定 mode 时，如果模板还没有被排班计划使用则直接修改，否则返回影响分析，由黑心选择迁移还是创建新版本
+// 排班计划使用的都是模板的快照，修改普通模板不会影响已有的排班计划；快照只能在排班计划发布之前通过 mode=migrate 显式迁移，
+// 发布之后快照就被冻结，只能通过 mode=version 创建新版本
+func (h *Handler) EditScheduleTemplateShifts(w http.ResponseWriter, r *http.Request) {
+	st := r.Context().Value(ScheduleTemplateCtx).(*domain.ScheduleTemplate)
+	mode := r.URL.Query().Get("mode")
+
+	var req struct {
+		Name   string `json:"name"` // 创建新版本时使用的名称，为空时自动生成
+		Shifts []struct {
+			ID                      int64                                        `json:"id"`
+			StartTime               string                                       `json:"startTime" validate:"required"`
+			EndTime                 string                                       `json:"endTime" validate:"required"`
+			Location                string                                       `json:"location"`
+			RequiredAssistantNumber int32                                        `json:"requiredAssistantNumber" validate:"required,gte=1"`
+			ApplicableDays          []int32                                      `json:"applicableDays" validate:"required,dive,gte=1"`
+			DayRequirements         []scheduleTemplateShiftDayRequirementRequest `json:"dayRequirements" validate:"dive"`
+		} `json:"shifts" validate:"required,dive"`
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	switch mode {
+	case "", "preview", "migrate", "version":
+	default:
+		h.errorResponse(w, r, "mode 只能是 preview、migrate 或 version")
+		return
+	}
+
+	edited := &domain.ScheduleTemplate{
+		Name:          st.Name,
+		Description:   st.Description,
+		RotationWeeks: st.RotationWeeks,
+		Shifts:        make([]domain.ScheduleTemplateShift, 0, len(req.Shifts)),
+	}
+	for _, shift := range req.Shifts {
+		edited.Shifts = append(edited.Shifts, domain.ScheduleTemplateShift{
+			ID:                      shift.ID,
+			StartTime:               shift.StartTime,
+			EndTime:                 shift.EndTime,
+			Location:                shift.Location,
+			RequiredAssistantNumber: shift.RequiredAssistantNumber,
+			ApplicableDays:          shift.ApplicableDays,
+			DayRequirements:         toScheduleTemplateShiftDayRequirements(shift.DayRequirements),
+		})
+	}
+
+	if err := utils.ValidateScheduleTemplateShiftTime(edited); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := utils.ValidateScheduleTemplateShiftRequirements(edited); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	changes, err := utils.DiffScheduleTemplateShifts(st, edited.Shifts)
+	if err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if changes.IsEmpty() {
+		h.errorResponse(w, r, "班次没有任何变化")
+		return
+	}
+
+	impacts, err := h.repository.GetScheduleTemplateImpacts(st.ID, changes)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+	preview := &domain.ScheduleTemplateEditPreview{
+		Changes: changes,
+		Impacts: impacts,
+	}
+
+	if mode == "preview" {
+		h.successResponse(w, r, "获取修改预览成功", preview)
+		return
+	}
+	// 快照只能在黑心确认影响之后显式迁移，不能直接修改
+	if mode == "" && (len(impacts) > 0 || st.IsSnapshot) {
+		h.errorResponseWithData(w, r, fmt.Sprintf("该模板已被 %d 个排班计划使用，请确认影响后选择迁移已有数据（mode=migrate）或者创建新版本（mode=version）", len(impacts)), preview)
+		return
+	}
+
+	if mode == "version" {
+		// 新版本的班次都视为新增
+		for i := range edited.Shifts {
+			edited.Shifts[i].ID = 0
+		}
+		edited.Name = req.Name
+		if edited.Name == "" {
+			edited.Name = fmt.Sprintf("%s（%s 修改）", st.Name, time.Now().Format("2006-01-02 15:04"))
+		}
+
+		if err := h.repository.CreateScheduleTemplate(edited); err != nil {
+			var pgErr *pgconn.PgError
+			switch {
+			case errors.As(err, &pgErr):
+				switch pgErr.ConstraintName {
+				case "schedule_templates_name_key":
+					h.errorResponse(w, r, "模板名称已存在")
+				default:
+					h.internalServerError(w, r, err)
+				}
+			default:
+				h.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		h.successResponse(w, r, "已创建新版本的模板", edited)
+		return
+	}
+
+	// 已经发布的排班计划的快照是冻结的，只能创建新版本
+	if st.IsSnapshot {
+		for _, impact := range impacts {
+			if impact.Status == domain.SchedulePlanStatusPublished || impact.Status == domain.SchedulePlanStatusActive {
+				h.errorResponseWithData(w, r, fmt.Sprintf("排班计划 %s 已发布，模板快照不能再修改，请创建新版本（mode=version）", impact.SchedulePlanName), preview)
+				return
+			}
+		}
+	}
+
+	// 已经归档的排班计划是历史记录，不应该被改动
+	for _, impact := range impacts {
+		if impact.Status == domain.SchedulePlanStatusArchived {
+			h.errorResponseWithData(w, r, fmt.Sprintf("排班计划 %s 已归档，无法迁移，请创建新版本（mode=version）", impact.SchedulePlanName), preview)
+			return
+		}
+	}
+	// 考勤和请假记录是历史记录，不能随着班次一起删除
+	for _, impact := range impacts {
+		if impact.BlocksShiftRemoval() {
+			h.errorResponseWithData(w, r, fmt.Sprintf("排班计划 %s 中被删除的班次上已有 %d 条考勤记录和 %d 条请假记录，无法删除这些班次", impact.SchedulePlanName, impact.RemovedShiftAttendance, impact.RemovedShiftLeaves), preview)
+			return
+		}
+	}
+
+	if err := h.repository.MigrateScheduleTemplateShifts(st, changes); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "模板已被修改，请重试")
+		case errors.Is(err, repository.ErrScheduleTemplateShiftHasRecords):
+			h.errorResponseWithData(w, r, err.Error(), preview)
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// 已经发布的排班计划需要根据新的班次重新生成具体的值班
+	for _, impact := range impacts {
+		plan, err := h.repository.GetSchedulePlanByID(impact.SchedulePlanID)
+		if err != nil {
+			h.internalServerError(w, r, err)
+			return
+		}
+		if err := h.syncShiftInstances(plan); err != nil {
+			h.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	updated, err := h.repository.GetScheduleTemplate(st.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "修改模板班次成功", updated)
+}
+
+// CloneScheduleTemplate 以现有模板（包括排班计划使用的快照）为基础创建一个新模板，可以逐个覆盖班次的属性
+func (h *Handler) CloneScheduleTemplate(w http.ResponseWriter, r *http.Request) {
+	st := r.Context().Value(ScheduleTemplateCtx).(*domain.ScheduleTemplate)
+
+	var req struct {
+		Name          string  `json:"name" validate:"required"`
+		Description   *string `json:"description"`
+		RotationWeeks *int32  `json:"rotationWeeks" validate:"omitnil,gte=1,lte=4"`
+		Overrides     []struct {
+			ID                      int64                                         `json:"id" validate:"required"` // 原模板中的班次
+			StartTime               *string                                       `json:"startTime"`
+			EndTime                 *string                                       `json:"endTime"`
+			Location                *string                                       `json:"location"`
+			RequiredAssistantNumber *int32                                        `json:"requiredAssistantNumber" validate:"omitnil,gte=1"`
+			ApplicableDays          *[]int32                                      `json:"applicableDays" validate:"omitnil,dive,gte=1"`
+			DayRequirements         *[]scheduleTemplateShiftDayRequirementRequest `json:"dayRequirements" validate:"omitnil,dive"`
+			Removed                 bool                                          `json:"removed"` // 为 true 时新模板中不包含该班次
+		} `json:"overrides" validate:"dive"`
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	clone := &domain.ScheduleTemplate{
+		Name:          req.Name,
+		Description:   st.Description,
+		RotationWeeks: st.RotationWeeks,
+		Shifts:        make([]domain.ScheduleTemplateShift, 0, len(st.Shifts)),
+	}
+	if req.Description != nil {
+		clone.Description = *req.Description
+	}
+	if req.RotationWeeks != nil {
+		clone.RotationWeeks = *req.RotationWeeks
+	}
+
+	for _, override := range req.Overrides {
+		if !slices.ContainsFunc(st.Shifts, func(shift domain.ScheduleTemplateShift) bool { return shift.ID == override.ID }) {
+			h.errorResponse(w, r, fmt.Sprintf("班次 %d 不属于该模板", override.ID))
+			return
+		}
+	}
+
+	for _, shift := range st.Shifts {
+		shift.ApplicableDays = slices.Clone(shift.ApplicableDays)
+		shift.DayRequirements = slices.Clone(shift.DayRequirements)
+
+		removed := false
+		for _, override := range req.Overrides {
+			if override.ID != shift.ID {
+				continue
+			}
+			if override.Removed {
+				removed = true
+				break
+			}
+			if override.StartTime != nil {
+				shift.StartTime = *override.StartTime
+			}
+			if override.EndTime != nil {
+				shift.EndTime = *override.EndTime
+			}
+			if override.Location != nil {
+				shift.Location = *override.Location
+			}
+			if override.RequiredAssistantNumber != nil {
+				shift.RequiredAssistantNumber = *override.RequiredAssistantNumber
+			}
+			if override.ApplicableDays != nil {
+				shift.ApplicableDays = slices.Clone(*override.ApplicableDays)
+			}
+			if override.DayRequirements != nil {
+				shift.DayRequirements = toScheduleTemplateShiftDayRequirements(*override.DayRequirements)
+			}
+		}
+		if removed {
+			continue
+		}
+
+		// 修改适用的天数之后，不再适用的天数上的人数要求也一并去掉
+		shift.DayRequirements = slices.DeleteFunc(shift.DayRequirements, func(requirement domain.ScheduleTemplateShiftDayRequirement) bool {
+			return !slices.Contains(shift.ApplicableDays, requirement.Day)
+		})
+
+		shift.ID = 0
+		clone.Shifts = append(clone.Shifts, shift)
+	}
+
+	if err := utils.ValidateScheduleTemplateShiftTime(clone); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := utils.ValidateScheduleTemplateShiftRequirements(clone); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	if err := h.repository.CreateScheduleTemplate(clone); err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.As(err, &pgErr):
+			switch pgErr.ConstraintName {
+			case "schedule_templates_name_key":
+				h.errorResponse(w, r, "模板名称已存在")
+			default:
+				h.internalServerError(w, r, err)
+			}
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	h.successResponse(w, r, "复制模板成功", clone)
+}
@@ -0,0 +1,426 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/timetable"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
+)
+
+func (h *Handler) shiftSwap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+		swapID, err := strconv.ParseInt(chi.URLParam(r, "swapID"), 10, 64)
+		if err != nil {
+			h.errorResponse(w, r, "换班申请ID无效")
+			return
+		}
+
+		swap, err := h.repository.GetShiftSwapByID(swapID)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				h.errorResponse(w, r, "换班申请不存在")
+			default:
+				h.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if swap.SchedulePlanID != plan.ID {
+			h.errorResponse(w, r, "换班申请不存在")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ShiftSwapCtx, swap)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// describeShiftSlot 将 (shift, day) 描述为 "周一 09:00-10:00" 的形式，轮换模板会带上第几周，用于邮件中展示
+func describeShiftSlot(template *domain.ScheduleTemplate, shiftID int64, day int32) string {
+	dayName := timetable.DayName(day, template.RotationWeeks)
+
+	for _, shift := range template.Shifts {
+		if shift.ID == shiftID {
+			return fmt.Sprintf("%s %s-%s", dayName, shift.StartTime, shift.EndTime)
+		}
+	}
+
+	return dayName
+}
+
+// notifyShiftSwap 给换班相关的人发送邮件通知
+func (h *Handler) notifyShiftSwap(plan *domain.SchedulePlan, swap *domain.ShiftSwap, title string, users map[int64]*domain.User, recipients ...*domain.User) error {
+	template, err := h.repository.GetScheduleTemplate(plan.ScheduleTemplateID)
+	if err != nil {
+		return err
+	}
+
+	data := domain.ShiftSwapMailData{
+		Title:            title,
+		PlanName:         plan.Name,
+		Type:             string(swap.Type),
+		Status:           string(swap.Status),
+		ProposerName:     users[swap.ProposerID].FullName,
+		CounterpartyName: users[swap.CounterpartyID].FullName,
+		Slot:             describeShiftSlot(template, swap.ShiftID, swap.Day),
+		Reason:           swap.Reason,
+	}
+	if swap.Type == domain.ShiftSwapTypeExchange {
+		data.CounterpartySlot = describeShiftSlot(template, *swap.CounterpartyShiftID, *swap.CounterpartyDay)
+	}
+
+	mailMessages := make([]domain.MailMessage, 0, len(recipients))
+	for _, recipient := range recipients {
+		data.FullName = recipient.FullName
+		mailMessages = append(mailMessages, domain.MailMessage{
+			Type: "shift_swap",
+			To:   recipient.Email,
+			Data: data,
+		})
+	}
+
+	return h.publishMails(mailMessages...)
+}
+
+// getUsersMap 获取所有用户，并以 ID 为键组织成 map
+func (h *Handler) getUsersMap() (map[int64]*domain.User, error) {
+	users, err := h.repository.GetAllUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	usersMap := make(map[int64]*domain.User, len(users))
+	for _, user := range users {
+		usersMap[user.ID] = user
+	}
+
+	return usersMap, nil
+}
+
+func getActiveBlackCores(users map[int64]*domain.User) []*domain.User {
+	blackCores := make([]*domain.User, 0)
+	for _, user := range users {
+		if user.Role == domain.RoleBlackCore && user.IsActive {
+			blackCores = append(blackCores, user)
+		}
+	}
+	return blackCores
+}
+
+func (h *Handler) CreateShiftSwap(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	var req struct {
+		Type                string `json:"type" validate:"required,oneof=转让 交换"`
+		CounterpartyID      int64  `json:"counterpartyID" validate:"required"`
+		ShiftID             int64  `json:"shiftID" validate:"required"`
+		Day                 int32  `json:"day" validate:"required,min=1"`
+		CounterpartyShiftID *int64 `json:"counterpartyShiftID" validate:"required_if=Type 交换"`
+		CounterpartyDay     *int32 `json:"counterpartyDay" validate:"required_if=Type 交换,omitempty,min=1"`
+		Reason              string `json:"reason"`
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	if req.CounterpartyID == myInfo.ID {
+		h.errorResponse(w, r, "不能和自己换班")
+		return
+	}
+
+	if plan.ActiveEndTime.Before(time.Now()) {
+		h.errorResponse(w, r, "该排班计划已结束，无法换班")
+		return
+	}
+
+	swap := &domain.ShiftSwap{
+		SchedulePlanID: plan.ID,
+		Type:           domain.ShiftSwapType(req.Type),
+		ProposerID:     myInfo.ID,
+		CounterpartyID: req.CounterpartyID,
+		ShiftID:        req.ShiftID,
+		Day:            req.Day,
+		Reason:         req.Reason,
+	}
+	if swap.Type == domain.ShiftSwapTypeExchange {
+		swap.CounterpartyShiftID = req.CounterpartyShiftID
+		swap.CounterpartyDay = req.CounterpartyDay
+	}
+
+	users, err := h.getUsersMap()
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	counterparty, exists := users[req.CounterpartyID]
+	if !exists {
+		h.errorResponse(w, r, "对方不存在")
+		return
+	}
+
+	// 先在当前的排班结果上试着应用一次，提前发现不合法的换班
+	schedulingResult, err := h.repository.GetSchedulingResultBySchedulePlanID(plan.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "该排班计划还没有排班结果")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	template, err := h.repository.GetScheduleTemplate(plan.ScheduleTemplateID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ApplyShiftSwap(schedulingResult, template, swap, myInfo, counterparty); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	if err := h.repository.CreateShiftSwap(swap); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	if err := h.notifyShiftSwap(plan, swap, fmt.Sprintf("%s 向您发起了换班申请，请及时处理。", myInfo.FullName), users, counterparty); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "发起换班申请成功", swap)
+}
+
+func (h *Handler) GetShiftSwaps(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	swaps, err := h.repository.GetShiftSwapsBySchedulePlanID(plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	// 黑心可以看到所有的换班申请，其他人只能看到和自己有关的
+	if myInfo.Role != domain.RoleBlackCore {
+		filtered := make([]*domain.ShiftSwap, 0)
+		for _, swap := range swaps {
+			if swap.ProposerID == myInfo.ID || swap.CounterpartyID == myInfo.ID {
+				filtered = append(filtered, swap)
+			}
+		}
+		swaps = filtered
+	}
+
+	h.successResponse(w, r, "获取换班申请成功", swaps)
+}
+
+func (h *Handler) GetShiftSwap(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	swap := r.Context().Value(ShiftSwapCtx).(*domain.ShiftSwap)
+
+	if myInfo.Role != domain.RoleBlackCore && swap.ProposerID != myInfo.ID && swap.CounterpartyID != myInfo.ID {
+		h.errorResponse(w, r, "权限不足")
+		return
+	}
+
+	h.successResponse(w, r, "获取换班申请成功", swap)
+}
+
+// completeShiftSwap 将换班应用到排班结果上并通知相关人员
+func (h *Handler) completeShiftSwap(w http.ResponseWriter, r *http.Request, swap *domain.ShiftSwap, reviewer *domain.User) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	users, err := h.getUsersMap()
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	schedulingResult, err := h.repository.GetSchedulingResultBySchedulePlanID(plan.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "该排班计划还没有排班结果")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	template, err := h.repository.GetScheduleTemplate(plan.ScheduleTemplateID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	// 从发起到现在排班结果可能已经变化，因此需要重新检查
+	if err := utils.ApplyShiftSwap(schedulingResult, template, swap, users[swap.ProposerID], users[swap.CounterpartyID]); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	swap.Status = domain.ShiftSwapStatusCompleted
+	if reviewer != nil {
+		swap.ReviewerID = &reviewer.ID
+	}
+
+	if err := h.repository.CompleteShiftSwap(swap, schedulingResult); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "排班结果或换班申请已被修改，请重试")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := h.syncShiftInstances(plan); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	if err := h.notifyShiftSwap(plan, swap, "换班已完成，排班表已更新。", users, users[swap.ProposerID], users[swap.CounterpartyID]); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "换班成功", swap)
+}
+
+// updateShiftSwapStatus 更新换班申请的状态（不涉及排班结果）并通知相关人员
+func (h *Handler) updateShiftSwapStatus(w http.ResponseWriter, r *http.Request, swap *domain.ShiftSwap, status domain.ShiftSwapStatus, reviewer *domain.User, title string, msg string) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	users, err := h.getUsersMap()
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	swap.Status = status
+	if reviewer != nil {
+		swap.ReviewerID = &reviewer.ID
+	}
+
+	if err := h.repository.UpdateShiftSwapStatus(swap); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "换班申请已被修改，请重试")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	recipients := []*domain.User{users[swap.ProposerID], users[swap.CounterpartyID]}
+	if status == domain.ShiftSwapStatusAwaitingApproval {
+		recipients = append(recipients, getActiveBlackCores(users)...)
+	}
+
+	if err := h.notifyShiftSwap(plan, swap, title, users, recipients...); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, msg, swap)
+}
+
+func (h *Handler) AcceptShiftSwap(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	swap := r.Context().Value(ShiftSwapCtx).(*domain.ShiftSwap)
+
+	if swap.CounterpartyID != myInfo.ID {
+		h.errorResponse(w, r, "只有换班对象才能接受换班申请")
+		return
+	}
+	if swap.Status != domain.ShiftSwapStatusPending {
+		h.errorResponse(w, r, fmt.Sprintf("换班申请%s，无法接受", swap.Status))
+		return
+	}
+
+	if h.config.ShiftSwap.RequireApproval {
+		h.updateShiftSwapStatus(w, r, swap, domain.ShiftSwapStatusAwaitingApproval, nil, fmt.Sprintf("%s 已接受换班申请，等待黑心审批。", myInfo.FullName), "已接受换班申请，等待审批")
+		return
+	}
+
+	h.completeShiftSwap(w, r, swap, nil)
+}
+
+func (h *Handler) DeclineShiftSwap(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	swap := r.Context().Value(ShiftSwapCtx).(*domain.ShiftSwap)
+
+	if swap.CounterpartyID != myInfo.ID {
+		h.errorResponse(w, r, "只有换班对象才能拒绝换班申请")
+		return
+	}
+	if swap.Status != domain.ShiftSwapStatusPending {
+		h.errorResponse(w, r, fmt.Sprintf("换班申请%s，无法拒绝", swap.Status))
+		return
+	}
+
+	h.updateShiftSwapStatus(w, r, swap, domain.ShiftSwapStatusDeclined, nil, fmt.Sprintf("%s 拒绝了换班申请。", myInfo.FullName), "已拒绝换班申请")
+}
+
+func (h *Handler) CancelShiftSwap(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	swap := r.Context().Value(ShiftSwapCtx).(*domain.ShiftSwap)
+
+	if swap.ProposerID != myInfo.ID {
+		h.errorResponse(w, r, "只有发起人才能取消换班申请")
+		return
+	}
+	if swap.Status != domain.ShiftSwapStatusPending && swap.Status != domain.ShiftSwapStatusAwaitingApproval {
+		h.errorResponse(w, r, fmt.Sprintf("换班申请%s，无法取消", swap.Status))
+		return
+	}
+
+	h.updateShiftSwapStatus(w, r, swap, domain.ShiftSwapStatusCancelled, nil, fmt.Sprintf("%s 取消了换班申请。", myInfo.FullName), "已取消换班申请")
+}
+
+func (h *Handler) ApproveShiftSwap(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	swap := r.Context().Value(ShiftSwapCtx).(*domain.ShiftSwap)
+
+	if swap.Status != domain.ShiftSwapStatusAwaitingApproval {
+		h.errorResponse(w, r, fmt.Sprintf("换班申请%s，无法审批", swap.Status))
+		return
+	}
+
+	h.completeShiftSwap(w, r, swap, myInfo)
+}
+
+func (h *Handler) RejectShiftSwap(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	swap := r.Context().Value(ShiftSwapCtx).(*domain.ShiftSwap)
+
+	if swap.Status != domain.ShiftSwapStatusAwaitingApproval {
+		h.errorResponse(w, r, fmt.Sprintf("换班申请%s，无法驳回", swap.Status))
+		return
+	}
+
+	h.updateShiftSwapStatus(w, r, swap, domain.ShiftSwapStatusRejected, myInfo, fmt.Sprintf("%s 驳回了换班申请。", myInfo.FullName), "已驳回换班申请")
+}
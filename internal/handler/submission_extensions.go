@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/lifecycle"
+)
+
+func (h *Handler) GetSubmissionExtensions(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	extensions, err := h.repository.GetSubmissionExtensionsBySchedulePlanID(plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "获取延长截止时间记录成功", extensions)
+}
+
+func (h *Handler) GrantSubmissionExtension(w http.ResponseWriter, r *http.Request) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	user := r.Context().Value(UserInfoCtx).(*domain.User)
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	var req struct {
+		Deadline time.Time `json:"deadline" validate:"required"`
+		Reason   string    `json:"reason"`
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	// 排班结果发布之后再提交也不会参与排班了
+	if lifecycle.IsPublished(plan) || plan.Status == domain.SchedulePlanStatusArchived {
+		h.errorResponse(w, r, fmt.Sprintf("排班计划当前状态为%s，无法延长截止时间", plan.Status))
+		return
+	}
+	if !user.IsActive {
+		h.errorResponse(w, r, fmt.Sprintf("%s 已离职", user.FullName))
+		return
+	}
+	isParticipant, err := h.repository.IsSchedulePlanParticipant(plan.ID, user.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+	if !isParticipant {
+		h.errorResponse(w, r, fmt.Sprintf("%s 不在该排班计划的参与名单中", user.FullName))
+		return
+	}
+	if !req.Deadline.After(plan.SubmissionEndTime) {
+		h.errorResponse(w, r, "延长后的截止时间必须晚于排班计划的截止时间")
+		return
+	}
+	if !req.Deadline.After(time.Now()) {
+		h.errorResponse(w, r, "延长后的截止时间必须晚于当前时间")
+		return
+	}
+
+	extension := &domain.SubmissionExtension{
+		SchedulePlanID: plan.ID,
+		UserID:         user.ID,
+		Deadline:       req.Deadline,
+		Reason:         req.Reason,
+		GrantedBy:      &myInfo.ID,
+	}
+	if err := h.repository.UpsertSubmissionExtension(extension); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, fmt.Sprintf("已将 %s 的截止时间延长", user.FullName), extension)
+}
+
+func (h *Handler) RevokeSubmissionExtension(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(UserInfoCtx).(*domain.User)
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	if _, err := h.repository.GetSubmissionExtension(plan.ID, user.ID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, fmt.Sprintf("%s 没有被延长截止时间", user.FullName))
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := h.repository.DeleteSubmissionExtension(plan.ID, user.ID); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "取消延长截止时间成功", nil)
+}
+
+func (h *Handler) reviewLateSubmission(w http.ResponseWriter, r *http.Request, status domain.LateSubmissionStatus) {
+	myInfo := r.Context().Value(MyInfoCtx).(*domain.User)
+	user := r.Context().Value(UserInfoCtx).(*domain.User)
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	submission, err := h.repository.GetAvailabilitySubmissionByUserIDAndSchedulePlanID(user.ID, plan.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, fmt.Sprintf("%s 还没有提交过空闲时间", user.FullName))
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if submission.LateStatus == nil {
+		h.errorResponse(w, r, "该提交不是逾期提交，无需审批")
+		return
+	}
+	if *submission.LateStatus != domain.LateSubmissionStatusPending {
+		h.errorResponse(w, r, fmt.Sprintf("该逾期提交%s", *submission.LateStatus))
+		return
+	}
+
+	submission.LateStatus = &status
+	submission.LateReviewerID = &myInfo.ID
+	if err := h.repository.ReviewLateSubmission(submission); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.errorResponse(w, r, "该提交已被修改，请重试")
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	h.successResponse(w, r, fmt.Sprintf("逾期提交%s", status), submission)
+}
+
+func (h *Handler) ApproveLateSubmission(w http.ResponseWriter, r *http.Request) {
+	h.reviewLateSubmission(w, r, domain.LateSubmissionStatusApproved)
+}
+
+func (h *Handler) RejectLateSubmission(w http.ResponseWriter, r *http.Request) {
+	h.reviewLateSubmission(w, r, domain.LateSubmissionStatusRejected)
+}
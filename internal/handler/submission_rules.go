@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/lifecycle"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
+)
+
+// GetSubmissionRules 获取排班计划的提交要求，所有助理都可以查看，方便在提交之前知道需要满足什么要求
+func (h *Handler) GetSubmissionRules(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	rules, err := h.repository.GetSubmissionRules(plan.ID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "获取提交要求成功", rules)
+}
+
+// SetSubmissionRules 设置排班计划的提交要求，会覆盖之前的所有要求，已经提交的空闲时间不会重新检查
+func (h *Handler) SetSubmissionRules(w http.ResponseWriter, r *http.Request) {
+	plan := r.Context().Value(SchedulePlanCtx).(*domain.SchedulePlan)
+
+	var req struct {
+		RoleRules []struct {
+			Role              domain.Role `json:"role" validate:"required,oneof=普通助理 资深助理 黑心"`
+			MinSlots          int32       `json:"minSlots" validate:"gte=0"`
+			MinPrincipalSlots int32       `json:"minPrincipalSlots" validate:"gte=0"`
+			MinDistinctDays   int32       `json:"minDistinctDays" validate:"gte=0"`
+		} `json:"roleRules" validate:"dive"`
+		RequiredShiftIDs []int64 `json:"requiredShiftIDs"`
+	}
+
+	if err := h.readJSON(r, &req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	// 排班结果发布之后再修改提交要求没有意义
+	if lifecycle.IsPublished(plan) || plan.Status == domain.SchedulePlanStatusArchived {
+		h.errorResponse(w, r, fmt.Sprintf("排班计划当前状态为%s，无法修改提交要求", plan.Status))
+		return
+	}
+
+	rules := &domain.SubmissionRules{
+		SchedulePlanID:   plan.ID,
+		RoleRules:        make([]domain.SubmissionRoleRule, 0, len(req.RoleRules)),
+		RequiredShiftIDs: make([]int64, 0, len(req.RequiredShiftIDs)),
+	}
+	for _, rule := range req.RoleRules {
+		rules.RoleRules = append(rules.RoleRules, domain.SubmissionRoleRule{
+			Role:              rule.Role,
+			MinSlots:          rule.MinSlots,
+			MinPrincipalSlots: rule.MinPrincipalSlots,
+			MinDistinctDays:   rule.MinDistinctDays,
+		})
+	}
+	rules.RequiredShiftIDs = append(rules.RequiredShiftIDs, req.RequiredShiftIDs...)
+
+	template, err := h.repository.GetScheduleTemplate(plan.ScheduleTemplateID)
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	if err := utils.ValidateSubmissionRulesWithTemplate(rules, template); err != nil {
+		h.badRequest(w, r, err)
+		return
+	}
+
+	if err := h.repository.SetSubmissionRules(rules); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, "设置提交要求成功", rules)
+}
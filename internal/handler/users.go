@@ -1,16 +1,25 @@
 package handler
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"database/sql"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/jackc/pgx/v5/pgconn"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/importer"
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -222,3 +231,193 @@ func (h *Handler) UpdateUserPassword(w http.ResponseWriter, r *http.Request) {
 
 	h.successResponse(w, r, "修改密码成功", nil)
 }
+
+func (h *Handler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+
+	// 同时支持 JSON 数组和上传 CSV 文件两种方式
+	var rows []*importer.UserRow
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := h.readJSON(r, &rows); err != nil {
+			h.badRequest(w, r, err)
+			return
+		}
+		for i, row := range rows {
+			row.Line = i + 1
+			row.Errors = make([]string, 0)
+		}
+	} else {
+		data, err := h.readUploadedFile(w, r, "file")
+		if err != nil {
+			h.badRequest(w, r, err)
+			return
+		}
+
+		rows, err = importer.ParseUsersCSV(bytes.NewReader(data))
+		if err != nil {
+			h.badRequest(w, r, err)
+			return
+		}
+	}
+
+	if len(rows) == 0 {
+		h.errorResponse(w, r, "没有需要导入的用户")
+		return
+	}
+
+	// 逐行校验格式
+	for _, row := range rows {
+		if err := h.validate.Struct(row); err != nil {
+			validationErrors, ok := err.(validator.ValidationErrors)
+			if !ok {
+				h.internalServerError(w, r, err)
+				return
+			}
+			for _, validationError := range validationErrors {
+				row.Errors = append(row.Errors, validationError.Translate(h.translator))
+			}
+		}
+	}
+
+	// 检查用户名和邮箱是否重复
+	existing, err := h.repository.GetAllUsers()
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+	importer.CheckDuplicateUsers(rows, existing)
+
+	preview := importer.NewUserImport(rows)
+
+	if dryRun {
+		h.successResponse(w, r, "校验完成，以下为预览结果", preview)
+		return
+	}
+
+	if preview.ErrorCount > 0 {
+		h.errorResponseWithData(w, r, fmt.Sprintf("有 %d 行存在错误，请修正后重新导入", preview.ErrorCount), preview)
+		return
+	}
+
+	// 为每个用户生成随机密码
+	users := make([]*domain.User, len(rows))
+	passwords := make([]string, len(rows))
+	for i, row := range rows {
+		passwords[i] = utils.GenerateRandomPassword(h.config.NewUser.PasswordLength)
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(passwords[i]), bcrypt.DefaultCost)
+		if err != nil {
+			h.internalServerError(w, r, err)
+			return
+		}
+
+		users[i] = &domain.User{
+			Username:     row.Username,
+			PasswordHash: string(hashedPassword),
+			FullName:     row.FullName,
+			Email:        row.Email,
+			Role:         domain.Role(row.Role),
+		}
+	}
+
+	if err := h.repository.CreateUsers(users); err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.As(err, &pgErr):
+			// 预览之后可能有人新建了用户，因此这里仍然需要处理唯一约束
+			switch {
+			case pgErr.ConstraintName == "users_username_key":
+				h.badRequest(w, r, errors.New("用户名已存在"))
+			case pgErr.ConstraintName == "users_email_key":
+				h.badRequest(w, r, errors.New("邮箱已存在"))
+			default:
+				h.internalServerError(w, r, err)
+			}
+		default:
+			h.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// 所有用户都创建成功后再统一发送邮件
+	mailMessages := make([]domain.MailMessage, len(users))
+	for i, user := range users {
+		mailMessages[i] = domain.MailMessage{
+			Type: "create_user",
+			To:   user.Email,
+			Data: domain.CreateUserMailData{
+				FullName: user.FullName,
+				Username: user.Username,
+				Password: passwords[i],
+			},
+		}
+	}
+
+	if err := h.publishMails(mailMessages...); err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	h.successResponse(w, r, fmt.Sprintf("成功导入 %d 个用户", len(users)), users)
+}
+
+func (h *Handler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		h.errorResponse(w, r, "不支持的导出格式")
+		return
+	}
+
+	users, err := h.repository.GetAllUsers()
+	if err != nil {
+		h.internalServerError(w, r, err)
+		return
+	}
+
+	slices.SortFunc(users, func(a, b *domain.User) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	filename := fmt.Sprintf("助理名单_%s", time.Now().Format("20060102"))
+
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(users, "", "  ")
+		if err != nil {
+			h.internalServerError(w, r, err)
+			return
+		}
+		h.writeFile(w, r, "application/json", filename+".json", false, data)
+	default:
+		var buf bytes.Buffer
+		buf.WriteString("\ufeff") // 加上 BOM，避免 Excel 打开时中文乱码
+
+		writer := csv.NewWriter(&buf)
+		_ = writer.Write([]string{"ID", "用户名", "姓名", "邮箱", "角色", "是否在职", "创建时间"})
+		for _, user := range users {
+			isActive := "是"
+			if !user.IsActive {
+				isActive = "否"
+			}
+			_ = writer.Write([]string{
+				strconv.FormatInt(user.ID, 10),
+				user.Username,
+				user.FullName,
+				user.Email,
+				string(user.Role),
+				isActive,
+				user.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			h.internalServerError(w, r, err)
+			return
+		}
+
+		h.writeFile(w, r, "text/csv; charset=utf-8", filename+".csv", false, buf.Bytes())
+	}
+}
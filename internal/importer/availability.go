@@ -0,0 +1,304 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// 问卷导出的 CSV 中固定的信息列
+const (
+	ColumnNetID    = "NetID"
+	ColumnFullName = "姓名"
+	ColumnEmail    = "邮箱"
+	ColumnRole     = "角色"
+)
+
+// 部分软件（例如 Excel）导出的 CSV 会在开头带有 BOM
+const utf8BOM = "\ufeff"
+
+// AvailabilityRow 表示 CSV 中一行（一个助理）的解析结果
+type AvailabilityRow struct {
+	Line     int                                 `json:"line"` // CSV 中的行号（从 1 开始，包含表头）
+	NetID    string                              `json:"netID"`
+	FullName string                              `json:"fullName"`
+	Email    string                              `json:"email"`
+	UserID   int64                               `json:"userID"` // 为 0 表示没有匹配到用户
+	Items    []domain.AvailabilitySubmissionItem `json:"items"`
+	Errors   []string                            `json:"errors"`
+}
+
+// AvailabilityImport 表示整个 CSV 的解析结果
+type AvailabilityImport struct {
+	ShiftColumns map[string]int64   `json:"shiftColumns"` // 表头 -> 模板班次 ID
+	Warnings     []string           `json:"warnings"`
+	Rows         []*AvailabilityRow `json:"rows"`
+	ValidCount   int                `json:"validCount"`
+	ErrorCount   int                `json:"errorCount"`
+}
+
+// HasErrors 判断是否存在解析失败的行
+func (ai *AvailabilityImport) HasErrors() bool {
+	return ai.ErrorCount > 0
+}
+
+// Submissions 将解析成功的行转换为提交记录
+func (ai *AvailabilityImport) Submissions(schedulePlanID int64) []*domain.AvailabilitySubmission {
+	submissions := make([]*domain.AvailabilitySubmission, 0, ai.ValidCount)
+	for _, row := range ai.Rows {
+		if len(row.Errors) > 0 {
+			continue
+		}
+		submissions = append(submissions, &domain.AvailabilitySubmission{
+			SchedulePlanID: schedulePlanID,
+			UserID:         row.UserID,
+			Items:          row.Items,
+		})
+	}
+	return submissions
+}
+
+// 班次列的表头：可选的值班点，后面跟着起止时间
+var shiftHeaderRegexp = regexp.MustCompile(`^(.*?)\s*(\d{1,2}:\d{2})\s*-\s*(\d{1,2}:\d{2})$`)
+
+// ParseShiftHeader 将形如 "09：00-10：00" 的表头解析为 "09:00:00" 和 "10:00:00"
+// 有多个值班点时表头可以在时间前注明值班点，例如 "图书馆 09：00-10：00"，没有注明时返回的值班点为空
+// 问卷导出的表头通常使用全角冒号，这里同时兼容半角冒号
+func ParseShiftHeader(header string) (string, string, string, error) {
+	normalized := strings.ReplaceAll(strings.TrimSpace(header), "：", ":")
+	normalized = strings.ReplaceAll(normalized, "－", "-")
+
+	matches := shiftHeaderRegexp.FindStringSubmatch(normalized)
+	if matches == nil {
+		return "", "", "", fmt.Errorf("无法解析班次列 %s", header)
+	}
+
+	times := make([]string, 2)
+	for i, part := range matches[2:] {
+		t, err := time.Parse("15:04", part)
+		if err != nil {
+			return "", "", "", fmt.Errorf("无法解析班次列 %s", header)
+		}
+		times[i] = t.Format("15:04:05")
+	}
+
+	return strings.TrimSpace(matches[1]), times[0], times[1], nil
+}
+
+// ParseAvailabilityCSV 解析问卷导出的空闲时间 CSV
+// 表头格式为 NetID,姓名,邮箱,角色,<班次列...>，班次列的值为逗号分隔的星期几，例如 "1, 2, 4"
+// 轮换模板中的天数是周期内的天数，例如单双周模板中第二周的周一为 8
+// 班次列通过起止时间（以及值班点）与模板中的班次对应，助理通过用户名（NetID）或邮箱与系统中的用户对应，并且必须在 participants 中
+func ParseAvailabilityCSV(r io.Reader, template *domain.ScheduleTemplate, users []*domain.User, participants []*domain.User) (*AvailabilityImport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	headers, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("CSV 文件为空")
+		}
+		return nil, fmt.Errorf("无法读取表头: %w", err)
+	}
+	if len(headers) > 0 {
+		headers[0] = strings.TrimPrefix(headers[0], utf8BOM)
+	}
+
+	ai := &AvailabilityImport{
+		ShiftColumns: make(map[string]int64),
+		Warnings:     make([]string, 0),
+		Rows:         make([]*AvailabilityRow, 0),
+	}
+
+	// 解析表头
+	infoColumns := make(map[string]int)
+	shiftColumns := make(map[int]*domain.ScheduleTemplateShift) // 列下标 -> 模板班次
+	for i, header := range headers {
+		header = strings.TrimSpace(header)
+		switch header {
+		case ColumnNetID, ColumnFullName, ColumnEmail, ColumnRole:
+			infoColumns[header] = i
+			continue
+		}
+
+		if !strings.Contains(header, "-") {
+			// 既不是信息列也不是班次列，直接忽略
+			ai.Warnings = append(ai.Warnings, fmt.Sprintf("忽略无法识别的列 %s", header))
+			continue
+		}
+
+		location, startTime, endTime, err := ParseShiftHeader(header)
+		if err != nil {
+			return nil, err
+		}
+
+		// 表头没有注明值班点时只按照时间对应，此时必须只有一个班次的时间相同
+		var matched *domain.ScheduleTemplateShift = nil
+		for j := range template.Shifts {
+			if template.Shifts[j].StartTime != startTime || template.Shifts[j].EndTime != endTime {
+				continue
+			}
+			if location != "" && template.Shifts[j].Location != location {
+				continue
+			}
+			if matched != nil {
+				return nil, fmt.Errorf("班次列 %s 对应模板中的多个班次，请在时间前注明值班点，例如 \"%s %s\"", header, template.Shifts[j].Location, header)
+			}
+			matched = &template.Shifts[j]
+		}
+		if matched == nil {
+			return nil, fmt.Errorf("班次列 %s 在模板中没有对应的班次", header)
+		}
+		for _, shift := range shiftColumns {
+			if shift.ID == matched.ID {
+				return nil, fmt.Errorf("班次列 %s 重复", header)
+			}
+		}
+
+		shiftColumns[i] = matched
+		ai.ShiftColumns[header] = matched.ID
+	}
+
+	if _, exists := infoColumns[ColumnNetID]; !exists {
+		if _, exists := infoColumns[ColumnEmail]; !exists {
+			return nil, fmt.Errorf("CSV 中必须包含 %s 列或 %s 列", ColumnNetID, ColumnEmail)
+		}
+	}
+	if len(shiftColumns) == 0 {
+		return nil, errors.New("CSV 中没有找到任何班次列")
+	}
+
+	// 模板中没有出现在 CSV 中的班次视为该助理在这些班次都没空
+	for _, shift := range template.Shifts {
+		found := false
+		for _, s := range shiftColumns {
+			if s.ID == shift.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ai.Warnings = append(ai.Warnings, fmt.Sprintf("模板中 %s 的班次在 CSV 中没有对应的列，将视为所有人都没空", strings.TrimSpace(fmt.Sprintf("%s %s-%s", shift.Location, shift.StartTime, shift.EndTime))))
+		}
+	}
+
+	usersByUsername := make(map[string]*domain.User, len(users))
+	usersByEmail := make(map[string]*domain.User, len(users))
+	for _, user := range users {
+		usersByUsername[user.Username] = user
+		usersByEmail[strings.ToLower(user.Email)] = user
+	}
+
+	// 解析数据行
+	seenUsers := make(map[int64]int) // userID -> 第一次出现的行号
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("无法读取第 %d 行: %w", line+1, err)
+		}
+		line++
+
+		get := func(column string) string {
+			i, exists := infoColumns[column]
+			if !exists || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := &AvailabilityRow{
+			Line:     line,
+			NetID:    get(ColumnNetID),
+			FullName: get(ColumnFullName),
+			Email:    get(ColumnEmail),
+			Items:    make([]domain.AvailabilitySubmissionItem, 0, len(template.Shifts)),
+			Errors:   make([]string, 0),
+		}
+
+		// 匹配用户
+		var user *domain.User = nil
+		byUsername := usersByUsername[row.NetID]
+		byEmail := usersByEmail[strings.ToLower(row.Email)]
+		switch {
+		case row.NetID == "" && row.Email == "":
+			row.Errors = append(row.Errors, "NetID 和邮箱都为空")
+		case byUsername != nil && byEmail != nil && byUsername.ID != byEmail.ID:
+			row.Errors = append(row.Errors, "NetID 和邮箱分别匹配到了不同的用户")
+		case byUsername != nil:
+			user = byUsername
+		case byEmail != nil:
+			user = byEmail
+		default:
+			row.Errors = append(row.Errors, "系统中不存在该用户")
+		}
+
+		if user != nil {
+			row.UserID = user.ID
+			if !user.IsActive {
+				row.Errors = append(row.Errors, "该助理已离职")
+			} else if !slices.ContainsFunc(participants, func(p *domain.User) bool { return p.ID == user.ID }) {
+				row.Errors = append(row.Errors, "该助理不在排班计划的参与名单中")
+			}
+			if first, exists := seenUsers[user.ID]; exists {
+				row.Errors = append(row.Errors, fmt.Sprintf("与第 %d 行是同一个用户", first))
+			} else {
+				seenUsers[user.ID] = line
+			}
+		}
+
+		// 解析每个班次的空闲天数
+		for _, shift := range template.Shifts {
+			item := domain.AvailabilitySubmissionItem{
+				ShiftID: shift.ID,
+				Days:    make([]int32, 0),
+			}
+
+			for i, s := range shiftColumns {
+				if s.ID != shift.ID || i >= len(record) {
+					continue
+				}
+
+				for _, day := range strings.FieldsFunc(record[i], func(r rune) bool {
+					return r == ',' || r == '，' || r == ' '
+				}) {
+					dayInt, err := strconv.Atoi(day)
+					if err != nil {
+						row.Errors = append(row.Errors, fmt.Sprintf("%s 列中的 %s 不是合法的星期", headers[i], day))
+						continue
+					}
+					if !slices.Contains(shift.ApplicableDays, int32(dayInt)) {
+						row.Errors = append(row.Errors, fmt.Sprintf("%s 列中的星期 %d 不在该班次的适用日期中", headers[i], dayInt))
+						continue
+					}
+					if !slices.Contains(item.Days, int32(dayInt)) {
+						item.Days = append(item.Days, int32(dayInt))
+					}
+				}
+			}
+
+			row.Items = append(row.Items, item)
+		}
+
+		if len(row.Errors) > 0 {
+			ai.ErrorCount++
+		} else {
+			ai.ValidCount++
+		}
+		ai.Rows = append(ai.Rows, row)
+	}
+
+	return ai, nil
+}
@@ -0,0 +1,196 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	ics "github.com/arran4/golang-ical"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
+)
+
+// CalendarExceptionRow 表示 ICS 中某个事件在某一天上展开后的解析结果
+type CalendarExceptionRow struct {
+	Summary   string                       `json:"summary"`
+	Date      string                       `json:"date"` // 2006-01-02
+	Type      domain.CalendarExceptionType `json:"type"`
+	WorkAsDay *int32                       `json:"workAsDay"`
+	Errors    []string                     `json:"errors"`
+}
+
+// CalendarExceptionImport 表示整个 ICS 文件的解析结果
+type CalendarExceptionImport struct {
+	Warnings   []string                `json:"warnings"`
+	Rows       []*CalendarExceptionRow `json:"rows"`
+	ValidCount int                     `json:"validCount"`
+	ErrorCount int                     `json:"errorCount"`
+}
+
+// HasErrors 判断是否存在解析失败的行
+func (ci *CalendarExceptionImport) HasErrors() bool {
+	return ci.ErrorCount > 0
+}
+
+// Exceptions 将解析成功的行转换为例外日期
+func (ci *CalendarExceptionImport) Exceptions(schedulePlanID int64) []*domain.CalendarException {
+	exceptions := make([]*domain.CalendarException, 0, ci.ValidCount)
+	for _, row := range ci.Rows {
+		if len(row.Errors) > 0 {
+			continue
+		}
+		date, _ := time.Parse("2006-01-02", row.Date)
+		exceptions = append(exceptions, &domain.CalendarException{
+			SchedulePlanID: schedulePlanID,
+			Date:           date,
+			Type:           row.Type,
+			WorkAsDay:      row.WorkAsDay,
+			Note:           row.Summary,
+		})
+	}
+	return exceptions
+}
+
+var weekdayPattern = regexp.MustCompile(`(?:周|星期)([一二三四五六日天])`)
+
+var weekdayNumbers = map[string]int32{
+	"一": 1, "二": 2, "三": 3, "四": 4, "五": 5, "六": 6, "日": 7, "天": 7,
+}
+
+// 休息日和调休上班的标记，先检查休息日的标记，避免 "春节停班"、"不上班" 之类的标题被当作调休上班
+var (
+	restDayMarkers = []string{"停班", "放假", "休息", "休假", "不上班", "（休）", "(休)"}
+	workdayMarkers = []string{"补班", "（班）", "(班)"}
+)
+
+// hasToken 判断 summary 按照空白和标点分隔之后是否有单独的 token，例如 "国庆节 班" 中的 "班"
+func hasToken(summary string, token string) bool {
+	return slices.Contains(strings.FieldsFunc(summary, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}), token)
+}
+
+// isWorkdayEvent 判断事件是否是调休上班，常见的节假日日历中会用单独的 "班"、"补班"、"（班）" 标记
+func isWorkdayEvent(summary string) bool {
+	for _, marker := range restDayMarkers {
+		if strings.Contains(summary, marker) {
+			return false
+		}
+	}
+	if hasToken(summary, "休") {
+		return false
+	}
+
+	for _, marker := range workdayMarkers {
+		if strings.Contains(summary, marker) {
+			return true
+		}
+	}
+	return hasToken(summary, "班")
+}
+
+// ParseCalendarICS 解析节假日日历的 ICS 文件，只保留落在排班计划生效期间内的日期
+// 调休上班的日期需要在标题或者描述中写明按照星期几排班（例如 "补班（按周一排班）"），否则使用 defaultWorkAsDay
+func ParseCalendarICS(r io.Reader, plan *domain.SchedulePlan, defaultWorkAsDay *int32) (*CalendarExceptionImport, error) {
+	calendar, err := ics.ParseCalendar(r)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析 ICS 文件：%w", err)
+	}
+
+	result := &CalendarExceptionImport{
+		Warnings: make([]string, 0),
+		Rows:     make([]*CalendarExceptionRow, 0),
+	}
+
+	firstDate := utils.DateOf(plan.ActiveStartTime)
+	lastDate := utils.DateOf(plan.ActiveEndTime)
+	seen := make(map[string]*CalendarExceptionRow)
+	outOfRange := 0
+
+	for _, event := range calendar.Events() {
+		summary := ""
+		if p := event.GetProperty(ics.ComponentPropertySummary); p != nil {
+			summary = strings.TrimSpace(p.Value)
+		}
+		description := ""
+		if p := event.GetProperty(ics.ComponentPropertyDescription); p != nil {
+			description = p.Value
+		}
+
+		start, err := event.GetAllDayStartAt()
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("事件 %s 没有合法的开始日期，已忽略", summary))
+			continue
+		}
+		start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+
+		// 全天事件的结束日期是不包含在内的
+		end, err := event.GetAllDayEndAt()
+		if err != nil {
+			end = start.AddDate(0, 0, 1)
+		}
+		end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+		if !end.After(start) {
+			end = start.AddDate(0, 0, 1)
+		}
+
+		exceptionType := domain.CalendarExceptionTypeClosed
+		var workAsDay *int32
+		if isWorkdayEvent(summary) {
+			exceptionType = domain.CalendarExceptionTypeWorkday
+			if matched := weekdayPattern.FindStringSubmatch(summary + " " + description); matched != nil {
+				day := weekdayNumbers[matched[1]]
+				workAsDay = &day
+			} else {
+				workAsDay = defaultWorkAsDay
+			}
+		}
+
+		for date := start; date.Before(end); date = date.AddDate(0, 0, 1) {
+			if date.Before(firstDate) || date.After(lastDate) {
+				outOfRange++
+				continue
+			}
+
+			row := &CalendarExceptionRow{
+				Summary:   summary,
+				Date:      date.Format("2006-01-02"),
+				Type:      exceptionType,
+				WorkAsDay: workAsDay,
+				Errors:    make([]string, 0),
+			}
+
+			if exceptionType == domain.CalendarExceptionTypeWorkday && workAsDay == nil {
+				row.Errors = append(row.Errors, "无法确定调休当天按照星期几排班，请在标题中注明或者指定默认星期")
+			}
+			if previous, exists := seen[row.Date]; exists {
+				row.Errors = append(row.Errors, fmt.Sprintf("和事件 %s 的日期重复", previous.Summary))
+			}
+			seen[row.Date] = row
+
+			result.Rows = append(result.Rows, row)
+		}
+	}
+
+	if outOfRange > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("有 %d 天不在排班计划的生效期间内，已忽略", outOfRange))
+	}
+
+	slices.SortStableFunc(result.Rows, func(a, b *CalendarExceptionRow) int {
+		return strings.Compare(a.Date, b.Date)
+	})
+
+	for _, row := range result.Rows {
+		if len(row.Errors) > 0 {
+			result.ErrorCount++
+		} else {
+			result.ValidCount++
+		}
+	}
+
+	return result, nil
+}
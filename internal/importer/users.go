@@ -0,0 +1,156 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// UserRow 表示批量导入中的一个用户
+type UserRow struct {
+	Line     int      `json:"line"` // CSV 中的行号或 JSON 数组中的序号（从 1 开始）
+	Username string   `json:"username" validate:"required"`
+	FullName string   `json:"fullName" validate:"required"`
+	Email    string   `json:"email" validate:"required,email"`
+	Role     string   `json:"role" validate:"required,oneof=普通助理 资深助理 黑心"`
+	Errors   []string `json:"errors"`
+}
+
+// UserImport 表示批量导入用户的结果
+type UserImport struct {
+	Rows       []*UserRow `json:"rows"`
+	ValidCount int        `json:"validCount"`
+	ErrorCount int        `json:"errorCount"`
+}
+
+// CSV 表头的别名，兼容英文表头和问卷导出的中文表头
+var userColumnAliases = map[string][]string{
+	"username": {"username", "用户名", ColumnNetID},
+	"fullName": {"fullName", ColumnFullName},
+	"email":    {"email", ColumnEmail},
+	"role":     {"role", ColumnRole},
+}
+
+// ParseUsersCSV 解析批量导入用户的 CSV
+func ParseUsersCSV(r io.Reader) ([]*UserRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	headers, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("CSV 文件为空")
+		}
+		return nil, fmt.Errorf("无法读取表头: %w", err)
+	}
+	if len(headers) > 0 {
+		headers[0] = strings.TrimPrefix(headers[0], utf8BOM)
+	}
+
+	columns := make(map[string]int)
+	for i, header := range headers {
+		for field, aliases := range userColumnAliases {
+			for _, alias := range aliases {
+				if strings.EqualFold(strings.TrimSpace(header), alias) {
+					columns[field] = i
+				}
+			}
+		}
+	}
+	for field, aliases := range userColumnAliases {
+		if _, exists := columns[field]; !exists {
+			return nil, fmt.Errorf("CSV 中缺少 %s 列", strings.Join(aliases, "/"))
+		}
+	}
+
+	rows := make([]*UserRow, 0)
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("无法读取第 %d 行: %w", line+1, err)
+		}
+		line++
+
+		get := func(field string) string {
+			i := columns[field]
+			if i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		rows = append(rows, &UserRow{
+			Line:     line,
+			Username: get("username"),
+			FullName: get("fullName"),
+			Email:    get("email"),
+			Role:     get("role"),
+			Errors:   make([]string, 0),
+		})
+	}
+
+	return rows, nil
+}
+
+// CheckDuplicateUsers 检查导入的用户之间以及与已有用户之间的用户名和邮箱是否重复
+// 对应数据库中的 users_username_key 和 users_email_key 约束
+func CheckDuplicateUsers(rows []*UserRow, existing []*domain.User) {
+	existingUsernames := make(map[string]bool, len(existing))
+	existingEmails := make(map[string]bool, len(existing))
+	for _, user := range existing {
+		existingUsernames[user.Username] = true
+		existingEmails[strings.ToLower(user.Email)] = true
+	}
+
+	seenUsernames := make(map[string]int) // username -> 第一次出现的行号
+	seenEmails := make(map[string]int)    // email -> 第一次出现的行号
+	for _, row := range rows {
+		email := strings.ToLower(row.Email)
+
+		if row.Username != "" {
+			if existingUsernames[row.Username] {
+				row.Errors = append(row.Errors, "用户名已存在")
+			}
+			if first, exists := seenUsernames[row.Username]; exists {
+				row.Errors = append(row.Errors, fmt.Sprintf("用户名与第 %d 行重复", first))
+			} else {
+				seenUsernames[row.Username] = row.Line
+			}
+		}
+
+		if email != "" {
+			if existingEmails[email] {
+				row.Errors = append(row.Errors, "邮箱已存在")
+			}
+			if first, exists := seenEmails[email]; exists {
+				row.Errors = append(row.Errors, fmt.Sprintf("邮箱与第 %d 行重复", first))
+			} else {
+				seenEmails[email] = row.Line
+			}
+		}
+	}
+}
+
+// NewUserImport 汇总每一行的校验结果
+func NewUserImport(rows []*UserRow) *UserImport {
+	ui := &UserImport{
+		Rows: rows,
+	}
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			ui.ErrorCount++
+		} else {
+			ui.ValidCount++
+		}
+	}
+	return ui
+}
@@ -0,0 +1,51 @@
+package instance
+
+import (
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
+)
+
+// Calendar 记录排班计划中的例外日期以及模板的轮换周期，用来确定每个日期按照周期内的哪一天排班
+type Calendar struct {
+	exceptions    map[string]*domain.CalendarException
+	rotationStart time.Time
+	rotationWeeks int32
+}
+
+// NewCalendar 创建排班计划的日历，计划没有指定轮换起始日期时以生效开始时间所在的周为第一周
+func NewCalendar(plan *domain.SchedulePlan, template *domain.ScheduleTemplate, exceptions []*domain.CalendarException) *Calendar {
+	calendar := &Calendar{
+		exceptions:    make(map[string]*domain.CalendarException, len(exceptions)),
+		rotationStart: utils.DateOf(plan.ActiveStartTime),
+		rotationWeeks: template.RotationWeeks,
+	}
+	if plan.RotationStartDate != nil {
+		calendar.rotationStart = *plan.RotationStartDate
+	}
+	for _, exception := range exceptions {
+		calendar.exceptions[exception.Date.Format("2006-01-02")] = exception
+	}
+	return calendar
+}
+
+// DayOf 返回 date 当天按照周期内的哪一天排班，第二个返回值为 false 时表示当天停班
+// 调休的日期按照指定的星期排班，所在的周仍然是 date 在轮换周期中的那一周
+func (c *Calendar) DayOf(date time.Time) (int32, bool) {
+	week := utils.RotationWeekOf(c.rotationStart, date, c.rotationWeeks)
+
+	exception, exists := c.exceptions[date.Format("2006-01-02")]
+	if !exists {
+		return utils.CycleDay(week, utils.DayOfWeek(date)), true
+	}
+
+	switch exception.Type {
+	case domain.CalendarExceptionTypeClosed:
+		return 0, false
+	case domain.CalendarExceptionTypeWorkday:
+		return utils.CycleDay(week, *exception.WorkAsDay), true
+	default:
+		return utils.CycleDay(week, utils.DayOfWeek(date)), true
+	}
+}
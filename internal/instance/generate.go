@@ -0,0 +1,100 @@
+package instance
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
+)
+
+// Generate 将排班结果在排班计划的生效期间内按日期展开为具体的值班
+// 停班的日期不生成值班，调休的日期按照指定的星期生成，轮换模板按照日期所在的周选择周期内对应的一天
+// 已批准的请假会被考虑在内：安排了代班人的由代班人值班，没有安排的则不生成
+func Generate(
+	plan *domain.SchedulePlan,
+	template *domain.ScheduleTemplate,
+	result *domain.SchedulingResult,
+	leaves []*domain.LeaveRequest,
+	calendar *Calendar,
+) ([]*domain.ShiftInstance, error) {
+	type leaveKey struct {
+		userID  int64
+		shiftID int64
+		date    string
+	}
+	approvedLeaves := make(map[leaveKey]*domain.LeaveRequest)
+	for _, leave := range leaves {
+		if leave.Status == domain.LeaveRequestStatusApproved {
+			approvedLeaves[leaveKey{leave.UserID, leave.ShiftID, leave.Date.Format("2006-01-02")}] = leave
+		}
+	}
+
+	items := make(map[int64]map[int32]domain.SchedulingResultShiftItem)
+	for _, shift := range result.Shifts {
+		items[shift.ShiftID] = make(map[int32]domain.SchedulingResultShiftItem)
+		for _, item := range shift.Items {
+			items[shift.ShiftID][item.Day] = item
+		}
+	}
+
+	instances := make([]*domain.ShiftInstance, 0)
+	lastDate := utils.DateOf(plan.ActiveEndTime)
+	for date := utils.DateOf(plan.ActiveStartTime); !date.After(lastDate); date = date.AddDate(0, 0, 1) {
+		day, open := calendar.DayOf(date)
+		if !open {
+			continue
+		}
+
+		for _, shift := range template.Shifts {
+			if !slices.Contains(shift.ApplicableDays, day) {
+				continue
+			}
+			item, exists := items[shift.ID][day]
+			if !exists {
+				continue
+			}
+
+			startAt, err := utils.AtClock(date, shift.StartTime)
+			if err != nil {
+				return nil, fmt.Errorf("班次 %d 的开始时间格式错误", shift.ID)
+			}
+			// 跨越午夜的班次在第二天结束，具体值班仍然算在开始的那一天
+			endDate := date
+			if utils.ShiftEndsNextDay(&shift) {
+				endDate = date.AddDate(0, 0, 1)
+			}
+			endAt, err := utils.AtClock(endDate, shift.EndTime)
+			if err != nil {
+				return nil, fmt.Errorf("班次 %d 的结束时间格式错误", shift.ID)
+			}
+
+			add := func(userID int64, isPrincipal bool) {
+				if leave, onLeave := approvedLeaves[leaveKey{userID, shift.ID, date.Format("2006-01-02")}]; onLeave {
+					if leave.SubstituteID == nil {
+						return
+					}
+					userID = *leave.SubstituteID
+				}
+				instances = append(instances, &domain.ShiftInstance{
+					SchedulePlanID: plan.ID,
+					ShiftID:        shift.ID,
+					Date:           date,
+					UserID:         userID,
+					IsPrincipal:    isPrincipal,
+					StartAt:        startAt,
+					EndAt:          endAt,
+				})
+			}
+
+			if item.PrincipalID != nil {
+				add(*item.PrincipalID, true)
+			}
+			for _, assistantID := range item.AssistantIDs {
+				add(assistantID, false)
+			}
+		}
+	}
+
+	return instances, nil
+}
@@ -0,0 +1,37 @@
+package instance
+
+import (
+	"fmt"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// ICS 将具体值班导出为 iCalendar，每次值班对应一个事件
+// 事件的起止时间直接使用具体值班的 StartAt 和 EndAt，因此跨越午夜的班次会正确地在第二天结束
+func ICS(instances []*domain.ShiftInstance, users map[int64]*domain.User) string {
+	cal := ics.NewCalendar()
+	cal.SetMethod(ics.MethodPublish)
+	cal.SetProductId("-//sysu-ecnc//shift-manager//CN")
+	cal.SetXWRCalName("值班日历")
+
+	now := time.Now()
+	for _, shiftInstance := range instances {
+		summary := "值班"
+		if shiftInstance.IsPrincipal {
+			summary = "值班（负责人）"
+		}
+		if user, exists := users[shiftInstance.UserID]; exists {
+			summary = fmt.Sprintf("%s：%s", summary, user.FullName)
+		}
+
+		event := cal.AddEvent(fmt.Sprintf("shift-instance-%d@shift-manager", shiftInstance.ID))
+		event.SetDtStampTime(now)
+		event.SetStartAt(shiftInstance.StartAt)
+		event.SetEndAt(shiftInstance.EndAt)
+		event.SetSummary(summary)
+	}
+
+	return cal.Serialize()
+}
@@ -0,0 +1,133 @@
+package lifecycle
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/repository"
+)
+
+// 排班计划的生命周期：
+// 草稿 -> 收集中 -> 已截止 -> 排班中 -> 已发布 -> 生效中 -> 已归档
+// 其中 草稿 -> 收集中、收集中 -> 已截止、已发布 -> 生效中、生效中 -> 已归档 会在对应的时间自动发生，
+// 也可以由黑心提前手动操作；另外允许少量的回退，用于重新开放提交或者撤回发布后修改排班结果
+var transitions = map[domain.SchedulePlanStatus][]domain.SchedulePlanStatus{
+	domain.SchedulePlanStatusDraft:      {domain.SchedulePlanStatusOpen},
+	domain.SchedulePlanStatusOpen:       {domain.SchedulePlanStatusClosed},
+	domain.SchedulePlanStatusClosed:     {domain.SchedulePlanStatusOpen, domain.SchedulePlanStatusScheduling},
+	domain.SchedulePlanStatusScheduling: {domain.SchedulePlanStatusClosed, domain.SchedulePlanStatusPublished},
+	domain.SchedulePlanStatusPublished:  {domain.SchedulePlanStatusScheduling, domain.SchedulePlanStatusActive},
+	domain.SchedulePlanStatusActive:     {domain.SchedulePlanStatusArchived},
+	domain.SchedulePlanStatusArchived:   {},
+}
+
+// IsValidStatus 判断是否是已知的状态
+func IsValidStatus(status domain.SchedulePlanStatus) bool {
+	_, exists := transitions[status]
+	return exists
+}
+
+// CanTransition 判断是否允许从 from 转换到 to
+func CanTransition(from, to domain.SchedulePlanStatus) bool {
+	return slices.Contains(transitions[from], to)
+}
+
+// ValidateTransition 检查排班计划能否手动转换到 to
+// hasResult 表示排班计划是否已经有排班结果，发布前必须要有排班结果
+func ValidateTransition(plan *domain.SchedulePlan, to domain.SchedulePlanStatus, hasResult bool, now time.Time) error {
+	if !CanTransition(plan.Status, to) {
+		return fmt.Errorf("排班计划不能从%s变为%s", plan.Status, to)
+	}
+
+	switch to {
+	case domain.SchedulePlanStatusOpen:
+		// 否则会马上被自动截止
+		if !plan.SubmissionEndTime.After(now) {
+			return fmt.Errorf("提交截止时间已过，请先延后提交截止时间")
+		}
+	case domain.SchedulePlanStatusPublished:
+		if !hasResult {
+			return fmt.Errorf("该排班计划还没有排班结果，无法发布")
+		}
+	}
+
+	return nil
+}
+
+// AllowSchedulingResultChange 判断排班计划当前是否允许修改排班结果
+func AllowSchedulingResultChange(plan *domain.SchedulePlan) bool {
+	return plan.Status == domain.SchedulePlanStatusClosed || plan.Status == domain.SchedulePlanStatusScheduling
+}
+
+// IsPublished 判断排班计划的排班结果是否已经发布（包括已经生效）
+func IsPublished(plan *domain.SchedulePlan) bool {
+	return plan.Status == domain.SchedulePlanStatusPublished || plan.Status == domain.SchedulePlanStatusActive
+}
+
+// ValidateEdit 检查排班计划的时间能否从 before 修改为 after
+// 截止提交之后开始排班就不能再修改提交时间；生效之后不能再修改生效开始时间和轮换起始日期，否则会改变已经发生的值班；
+// 已归档的排班计划是历史记录，不能再修改任何时间
+func ValidateEdit(before, after *domain.SchedulePlan) error {
+	submissionChanged := !before.SubmissionStartTime.Equal(after.SubmissionStartTime) || !before.SubmissionEndTime.Equal(after.SubmissionEndTime)
+	activeStartChanged := !before.ActiveStartTime.Equal(after.ActiveStartTime) || !sameDate(before.RotationStartDate, after.RotationStartDate)
+	activeEndChanged := !before.ActiveEndTime.Equal(after.ActiveEndTime)
+
+	switch before.Status {
+	case domain.SchedulePlanStatusScheduling, domain.SchedulePlanStatusPublished:
+		if submissionChanged {
+			return fmt.Errorf("排班计划当前状态为%s，无法修改提交时间", before.Status)
+		}
+	case domain.SchedulePlanStatusActive:
+		if submissionChanged || activeStartChanged {
+			return fmt.Errorf("排班计划当前状态为%s，只能修改生效结束时间", before.Status)
+		}
+	case domain.SchedulePlanStatusArchived:
+		if submissionChanged || activeStartChanged || activeEndChanged {
+			return fmt.Errorf("排班计划当前状态为%s，无法修改时间", before.Status)
+		}
+	}
+
+	return nil
+}
+
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// DeriveStatus 在修改提交时间之后根据当前时间重新确定排班计划在提交阶段的状态
+// 例如已截止的排班计划把提交截止时间延后之后应该重新开放提交，不在提交阶段的排班计划保持不变
+func DeriveStatus(plan *domain.SchedulePlan, now time.Time) domain.SchedulePlanStatus {
+	switch plan.Status {
+	case domain.SchedulePlanStatusDraft, domain.SchedulePlanStatusOpen, domain.SchedulePlanStatusClosed:
+		switch {
+		case now.Before(plan.SubmissionStartTime):
+			return domain.SchedulePlanStatusDraft
+		case now.Before(plan.SubmissionEndTime):
+			return domain.SchedulePlanStatusOpen
+		default:
+			return domain.SchedulePlanStatusClosed
+		}
+	default:
+		return plan.Status
+	}
+}
+
+// Advance 自动推进排班计划的状态，适合作为后台任务定时执行
+func Advance(repo *repository.Repository) func(now time.Time) error {
+	return func(now time.Time) error {
+		ids, err := repo.AdvanceSchedulePlanStatuses(now)
+		if err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			slog.Info("已自动推进排班计划状态", "schedulePlanIDs", ids)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,35 @@
+package mailqueue
+
+import (
+	"context"
+	"encoding/json"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// Publish 将邮件序列化后批量发送到 email_queue 中，由 mail worker 负责真正发送
+func Publish(ctx context.Context, ch *amqp.Channel, mailMessages ...domain.MailMessage) error {
+	for _, mailMessage := range mailMessages {
+		mailData, err := json.Marshal(mailMessage)
+		if err != nil {
+			return err
+		}
+
+		if err := ch.PublishWithContext(
+			ctx,
+			"",
+			"email_queue",
+			true,
+			false,
+			amqp.Publishing{
+				ContentType: "application/json",
+				Body:        mailData,
+			},
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package payroll
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var header = []string{"用户名", "姓名", "角色", "排班工时", "请假工时", "代班工时", "计薪工时", "时薪", "工资"}
+
+func formatFloat(x float64) string {
+	return strconv.FormatFloat(x, 'f', 2, 64)
+}
+
+func (report *Report) records() [][]any {
+	records := make([][]any, 0, len(report.Rows)+1)
+	for _, row := range report.Rows {
+		records = append(records, []any{
+			row.Username,
+			row.FullName,
+			string(row.Role),
+			row.ScheduledHours,
+			row.LeaveHours,
+			row.SubstituteHours,
+			row.WorkedHours,
+			row.HourlyRate,
+			row.Pay,
+		})
+	}
+	records = append(records, []any{"合计", "", "", "", "", "", report.TotalHours, "", report.TotalPay})
+	return records
+}
+
+// WriteCSV 导出为 CSV，带 BOM 以便 Excel 正确识别中文
+func (report *Report) WriteCSV(w io.Writer) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	_ = writer.Write(header)
+	for _, record := range report.records() {
+		line := make([]string, len(record))
+		for i, v := range record {
+			switch v := v.(type) {
+			case float64:
+				line[i] = formatFloat(v)
+			default:
+				line[i] = v.(string)
+			}
+		}
+		_ = writer.Write(line)
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// WriteXLSX 导出为 Excel 工作簿
+func (report *Report) WriteXLSX(w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := report.Month
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+
+	headerRow := make([]any, len(header))
+	for i, h := range header {
+		headerRow[i] = h
+	}
+	if err := f.SetSheetRow(sheet, "A1", &headerRow); err != nil {
+		return err
+	}
+
+	for i, record := range report.records() {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, cell, &record); err != nil {
+			return err
+		}
+	}
+
+	if err := f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+
+	return f.Write(w)
+}
@@ -0,0 +1,74 @@
+package payroll
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/attendance"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/config"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/repository"
+)
+
+func RatesFromConfig(cfg *config.Config) Rates {
+	return Rates{
+		domain.RoleNormalAssistant: cfg.Payroll.NormalAssistantRate,
+		domain.RoleSeniorAssistant: cfg.Payroll.SeniorAssistantRate,
+		domain.RoleBlackCore:       cfg.Payroll.BlackCoreRate,
+	}
+}
+
+// Generate 从数据库中读取 month 所在月份涉及到的排班计划，并生成工时报表
+func Generate(repo *repository.Repository, cfg *config.Config, month time.Time) (*Report, error) {
+	plans, err := repo.GetAllSchedulePlans()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]*PlanData, 0)
+	for _, plan := range plans {
+		if !InMonth(plan, month) {
+			continue
+		}
+
+		d := &PlanData{Plan: plan}
+
+		d.Template, err = repo.GetScheduleTemplate(plan.ScheduleTemplateID)
+		if err != nil {
+			return nil, err
+		}
+
+		d.Result, err = repo.GetSchedulingResultBySchedulePlanID(plan.ID)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				return nil, err
+			}
+			d.Result = nil
+		}
+
+		d.Leaves, err = repo.GetLeaveRequestsBySchedulePlanID(plan.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		d.Exceptions, err = repo.GetCalendarExceptionsBySchedulePlanID(plan.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		d.Instances, err = repo.GetShiftInstancesBySchedulePlanID(plan.ID, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, d)
+	}
+
+	users, err := repo.GetAllUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	return Build(month, data, users, RatesFromConfig(cfg), time.Now(), attendance.RulesFromConfig(cfg))
+}
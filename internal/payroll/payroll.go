@@ -0,0 +1,209 @@
+package payroll
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/attendance"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/instance"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/lifecycle"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
+)
+
+// Rates 各个角色的时薪（元/小时）
+type Rates map[domain.Role]float64
+
+// PlanData 计算工时所需要的一个排班计划的全部数据
+type PlanData struct {
+	Plan       *domain.SchedulePlan
+	Template   *domain.ScheduleTemplate
+	Result     *domain.SchedulingResult // 为空表示还没有排班结果
+	Leaves     []*domain.LeaveRequest
+	Exceptions []*domain.CalendarException
+	Instances  []*domain.ShiftInstance // 已经生成的具体值班（包含考勤）
+}
+
+type Row struct {
+	UserID          int64       `json:"userID"`
+	Username        string      `json:"username"`
+	FullName        string      `json:"fullName"`
+	Role            domain.Role `json:"role"`
+	ScheduledHours  float64     `json:"scheduledHours"`  // 按排班结果应值班的工时
+	LeaveHours      float64     `json:"leaveHours"`      // 已批准请假的工时
+	SubstituteHours float64     `json:"substituteHours"` // 代班的工时
+	WorkedHours     float64     `json:"workedHours"`     // 实际计薪的工时，有考勤时以考勤为准
+	HourlyRate      float64     `json:"hourlyRate"`
+	Pay             float64     `json:"pay"`
+}
+
+type Report struct {
+	Month      string    `json:"month"` // 2006-01
+	Rows       []*Row    `json:"rows"`
+	TotalHours float64   `json:"totalHours"`
+	TotalPay   float64   `json:"totalPay"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// InMonth 判断排班计划的生效期间是否和 month 所在的月份有交集
+func InMonth(plan *domain.SchedulePlan, month time.Time) bool {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+	return !utils.DateOf(plan.ActiveStartTime).After(last) && !utils.DateOf(plan.ActiveEndTime).Before(first)
+}
+
+func inMonth(date time.Time, month time.Time) bool {
+	return date.Year() == month.Year() && date.Month() == month.Month()
+}
+
+func hoursBetween(start, end time.Time) float64 {
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start).Hours()
+}
+
+// workedHours 根据考勤计算一次值班的计薪工时，实际的签到签退时间会被截断在班次时间之内
+// 缺勤以及还没有开始的值班不计工时；被黑心修正为非缺勤但缺少签到签退时间的，按班次时间计算
+func workedHours(shiftInstance *domain.ShiftInstance, now time.Time, rules attendance.Rules) float64 {
+	switch attendance.Evaluate(shiftInstance, now, rules) {
+	case domain.AttendanceStatusAbsent, domain.AttendanceStatusPending:
+		return 0
+	}
+
+	start, end := shiftInstance.StartAt, shiftInstance.EndAt
+	if shiftInstance.CheckInAt != nil && shiftInstance.CheckInAt.After(start) {
+		start = *shiftInstance.CheckInAt
+	}
+	if shiftInstance.CheckOutAt != nil && shiftInstance.CheckOutAt.Before(end) {
+		end = *shiftInstance.CheckOutAt
+	}
+	return hoursBetween(start, end)
+}
+
+// Build 汇总 month 所在月份每个人的工时和工资
+// 有考勤记录的值班以考勤为准，没有的则按照已经发布的排班结果（已经扣除请假、加上代班）计算
+func Build(month time.Time, plans []*PlanData, users []*domain.User, rates Rates, now time.Time, rules attendance.Rules) (*Report, error) {
+	rows := make(map[int64]*Row)
+	getRow := func(userID int64) *Row {
+		if _, exists := rows[userID]; !exists {
+			rows[userID] = &Row{UserID: userID}
+		}
+		return rows[userID]
+	}
+
+	type instanceKey struct {
+		shiftID int64
+		date    string
+		userID  int64
+	}
+
+	for _, data := range plans {
+		if data.Result == nil {
+			continue
+		}
+		// 只有发布过的排班结果才会计算工时，还在排班中的结果可能还会修改，也没有人会按照它值班
+		if !lifecycle.IsPublished(data.Plan) && data.Plan.Status != domain.SchedulePlanStatusArchived {
+			continue
+		}
+
+		shiftHours := make(map[int64]float64)
+		for _, shift := range data.Template.Shifts {
+			// 跨越午夜的班次在第二天结束
+			start, end, err := utils.ShiftClockRange(&shift)
+			if err != nil {
+				return nil, err
+			}
+			shiftHours[shift.ID] = (end - start).Hours()
+		}
+
+		calendar := instance.NewCalendar(data.Plan, data.Template, data.Exceptions)
+
+		// 不考虑请假的排班
+		scheduled, err := instance.Generate(data.Plan, data.Template, data.Result, nil, calendar)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range scheduled {
+			if inMonth(s.Date, month) {
+				getRow(s.UserID).ScheduledHours += shiftHours[s.ShiftID]
+			}
+		}
+
+		for _, leave := range data.Leaves {
+			if leave.Status != domain.LeaveRequestStatusApproved || !inMonth(leave.Date, month) {
+				continue
+			}
+			getRow(leave.UserID).LeaveHours += shiftHours[leave.ShiftID]
+			if leave.SubstituteID != nil {
+				getRow(*leave.SubstituteID).SubstituteHours += shiftHours[leave.ShiftID]
+			}
+		}
+
+		// 先按考勤计算，再补上还没有生成具体值班并且已经结束的部分，还没有结束的只算在排班工时中
+		counted := make(map[instanceKey]bool)
+		for _, shiftInstance := range data.Instances {
+			if !inMonth(shiftInstance.Date, month) {
+				continue
+			}
+			getRow(shiftInstance.UserID).WorkedHours += workedHours(shiftInstance, now, rules)
+			counted[instanceKey{shiftInstance.ShiftID, shiftInstance.Date.Format("2006-01-02"), shiftInstance.UserID}] = true
+		}
+
+		expected, err := instance.Generate(data.Plan, data.Template, data.Result, data.Leaves, calendar)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range expected {
+			if !inMonth(e.Date, month) || counted[instanceKey{e.ShiftID, e.Date.Format("2006-01-02"), e.UserID}] {
+				continue
+			}
+			if e.EndAt.After(now) {
+				continue
+			}
+			getRow(e.UserID).WorkedHours += shiftHours[e.ShiftID]
+		}
+	}
+
+	report := &Report{
+		Month:     month.Format("2006-01"),
+		Rows:      make([]*Row, 0, len(rows)),
+		CreatedAt: now,
+	}
+
+	for _, user := range users {
+		row, exists := rows[user.ID]
+		if !exists {
+			continue
+		}
+		row.Username = user.Username
+		row.FullName = user.FullName
+		row.Role = user.Role
+		row.HourlyRate = rates[user.Role]
+		row.ScheduledHours = round(row.ScheduledHours)
+		row.LeaveHours = round(row.LeaveHours)
+		row.SubstituteHours = round(row.SubstituteHours)
+		row.WorkedHours = round(row.WorkedHours)
+		row.Pay = round(row.WorkedHours * row.HourlyRate)
+
+		report.Rows = append(report.Rows, row)
+		report.TotalHours += row.WorkedHours
+		report.TotalPay += row.Pay
+	}
+
+	report.TotalHours = round(report.TotalHours)
+	report.TotalPay = round(report.TotalPay)
+
+	slices.SortFunc(report.Rows, func(a, b *Row) int {
+		return strings.Compare(a.Username, b.Username)
+	})
+
+	return report, nil
+}
+
+// round 保留两位小数
+func round(x float64) float64 {
+	return math.Round(x*100) / 100
+}
@@ -0,0 +1,120 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/config"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/mailqueue"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/repository"
+)
+
+// DueHoursBefore 返回当前应当发送的提醒对应的“截止前多少小时”
+// 如果同时满足多个提醒（例如服务刚启动时距离截止只剩 5 小时，48 小时和 6 小时的提醒都已到期），只发送最近的那一个
+func DueHoursBefore(plan *domain.SchedulePlan, hoursBefore []int, now time.Time) (int, bool) {
+	remaining := plan.SubmissionEndTime.Sub(now)
+	if remaining <= 0 {
+		return 0, false
+	}
+
+	due := slices.DeleteFunc(slices.Clone(hoursBefore), func(h int) bool {
+		return h <= 0 || remaining > time.Duration(h)*time.Hour
+	})
+	if len(due) == 0 {
+		return 0, false
+	}
+
+	return slices.Min(due), true
+}
+
+// Send 检查所有正在收集空闲时间的排班计划，向参与名单中还没有提交的在职用户发送提醒邮件，适合作为后台任务定时执行
+func Send(repo *repository.Repository, cfg *config.Config, ch *amqp.Channel) func(now time.Time) error {
+	return func(now time.Time) error {
+		plans, err := repo.GetAllSchedulePlans()
+		if err != nil {
+			return err
+		}
+
+		for _, plan := range plans {
+			if plan.Status != domain.SchedulePlanStatusOpen {
+				continue
+			}
+
+			hoursBefore, ok := DueHoursBefore(plan, cfg.SubmissionReminder.HoursBefore, now)
+			if !ok {
+				continue
+			}
+
+			if err := remind(repo, cfg, ch, plan, hoursBefore, now); err != nil {
+				return fmt.Errorf("排班计划 %d 发送提醒失败: %w", plan.ID, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+func remind(repo *repository.Repository, cfg *config.Config, ch *amqp.Channel, plan *domain.SchedulePlan, hoursBefore int, now time.Time) error {
+	users, err := repo.GetUsersWithoutSubmission(plan.ID)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	userIDs := make([]int64, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	// 先登记再发送，已经登记过的用户不会再次收到同一条提醒
+	claimed, err := repo.ClaimSubmissionReminders(plan.ID, hoursBefore, userIDs)
+	if err != nil {
+		return err
+	}
+	if len(claimed) == 0 {
+		return nil
+	}
+
+	hoursLeft := int(math.Ceil(plan.SubmissionEndTime.Sub(now).Hours()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.RabbitMQ.PublishTimeout)*time.Second)
+	defer cancel()
+
+	recipients := slices.DeleteFunc(users, func(user *domain.User) bool {
+		return !slices.Contains(claimed, user.ID)
+	})
+	for i, user := range recipients {
+		mailMessage := domain.MailMessage{
+			Type: "submission_reminder",
+			To:   user.Email,
+			Data: domain.SubmissionReminderMailData{
+				FullName:          user.FullName,
+				PlanName:          plan.Name,
+				SubmissionEndTime: plan.SubmissionEndTime.Local().Format("2006-01-02 15:04"),
+				HoursLeft:         hoursLeft,
+			},
+		}
+		if err := mailqueue.Publish(ctx, ch, mailMessage); err != nil {
+			// 撤销还没有投递的登记，下一次检查时重新发送
+			unsent := make([]int64, 0, len(recipients)-i)
+			for _, u := range recipients[i:] {
+				unsent = append(unsent, u.ID)
+			}
+			if releaseErr := repo.ReleaseSubmissionReminders(plan.ID, hoursBefore, unsent); releaseErr != nil {
+				slog.Error("撤销提醒记录失败", "schedulePlanID", plan.ID, "error", releaseErr)
+			}
+			return err
+		}
+	}
+
+	slog.Info("已发送提交空闲时间提醒", "schedulePlanID", plan.ID, "hoursBefore", hoursBefore, "count", len(recipients))
+	return nil
+}
@@ -1,14 +1,21 @@
 package repository
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
+	"slices"
 	"time"
 
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
 )
 
 func (r *Repository) InsertAvailabilitySubmission(submission *domain.AvailabilitySubmission) error {
+	return r.InsertAvailabilitySubmissions([]*domain.AvailabilitySubmission{submission})
+}
+
+// InsertAvailabilitySubmissions 在同一个事务中插入多条提交记录，任意一条失败则全部回滚
+func (r *Repository) InsertAvailabilitySubmissions(submissions []*domain.AvailabilitySubmission) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
 	defer cancel()
 
@@ -20,20 +27,56 @@ func (r *Repository) InsertAvailabilitySubmission(submission *domain.Availabilit
 		_ = tx.Rollback()
 	}()
 
-	// 先把原先的记录删除再插入
-	query := `DELETE FROM availability_submissions WHERE user_id = $1 AND schedule_plan_id = $2`
+	// 按照用户 ID 的顺序加锁，避免并发的批量导入互相死锁
+	sorted := slices.Clone(submissions)
+	slices.SortFunc(sorted, func(a, b *domain.AvailabilitySubmission) int {
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	for _, submission := range sorted {
+		if err := insertAvailabilitySubmission(ctx, tx, submission); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func insertAvailabilitySubmission(ctx context.Context, tx *sql.Tx, submission *domain.AvailabilitySubmission) error {
+	// 先锁住用户，使同一个用户并发的提交依次计算 revision，否则会在 availability_submissions_revision_key 上冲突
+	// 第一次提交时还没有提交记录可以锁，因此锁的是用户本身；NO KEY UPDATE 不会阻塞其它表对用户的外键检查
+	query := `SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE`
+	var userID int64
+	if err := tx.QueryRowContext(ctx, query, submission.UserID).Scan(&userID); err != nil {
+		return err
+	}
+
+	// 原先的记录不删除，只标记为已被取代，以便之后查看历史版本
+	query = `
+		UPDATE availability_submissions
+		SET superseded_at = NOW()
+		WHERE user_id = $1 AND schedule_plan_id = $2 AND superseded_at IS NULL
+	`
 	if _, err := tx.ExecContext(ctx, query, submission.UserID, submission.SchedulePlanID); err != nil {
 		return err
 	}
 
 	query = `
-		INSERT INTO availability_submissions (user_id, schedule_plan_id)
-		VALUES ($1, $2)
-		RETURNING id, created_at, version
+		INSERT INTO availability_submissions (user_id, schedule_plan_id, revision, late_status)
+		SELECT $1, $2, COALESCE(MAX(revision), 0) + 1, $3
+		FROM availability_submissions
+		WHERE user_id = $1 AND schedule_plan_id = $2
+		RETURNING id, revision, created_at, version
 	`
-	if err := tx.QueryRowContext(ctx, query, submission.UserID, submission.SchedulePlanID).Scan(&submission.ID, &submission.CreatedAt, &submission.Version); err != nil {
+	dst := []any{&submission.ID, &submission.Revision, &submission.CreatedAt, &submission.Version}
+	if err := tx.QueryRowContext(ctx, query, submission.UserID, submission.SchedulePlanID, submission.LateStatus).Scan(dst...); err != nil {
 		return err
 	}
+	submission.SupersededAt = nil
+	submission.LateReviewerID = nil
 
 	for _, item := range submission.Items {
 		query := `
@@ -57,10 +100,6 @@ func (r *Repository) InsertAvailabilitySubmission(submission *domain.Availabilit
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return err
-	}
-
 	return nil
 }
 
@@ -69,9 +108,9 @@ func (r *Repository) GetAvailabilitySubmissionByUserIDAndSchedulePlanID(userID i
 	defer cancel()
 
 	query := `
-		SELECT id, created_at, version
+		SELECT id, revision, late_status, late_reviewer_id, created_at, version
 		FROM availability_submissions
-		WHERE user_id = $1 AND schedule_plan_id = $2
+		WHERE user_id = $1 AND schedule_plan_id = $2 AND superseded_at IS NULL
 	`
 
 	submission := &domain.AvailabilitySubmission{
@@ -79,7 +118,7 @@ func (r *Repository) GetAvailabilitySubmissionByUserIDAndSchedulePlanID(userID i
 		SchedulePlanID: schedulePlanID,
 	}
 
-	if err := r.dbpool.QueryRowContext(ctx, query, userID, schedulePlanID).Scan(&submission.ID, &submission.CreatedAt, &submission.Version); err != nil {
+	if err := r.dbpool.QueryRowContext(ctx, query, userID, schedulePlanID).Scan(&submission.ID, &submission.Revision, &submission.LateStatus, &submission.LateReviewerID, &submission.CreatedAt, &submission.Version); err != nil {
 		return nil, err
 	}
 
@@ -137,51 +176,119 @@ func (r *Repository) GetAvailabilitySubmissionByUserIDAndSchedulePlanID(userID i
 	return submission, nil
 }
 
+// effectiveSubmissionsQuery 每个用户在排班计划 $1 中当前生效的提交
+// 逾期提交只有在被批准之后才会生效，在此之前仍然使用该用户上一次生效的提交
+// 排班、提交统计和提醒都使用这个规则，保证统计的和排班实际使用的是同一份提交
+const effectiveSubmissionsQuery = `
+	SELECT DISTINCT ON (user_id) *
+	FROM availability_submissions
+	WHERE schedule_plan_id = $1 AND (late_status IS NULL OR late_status = '已批准')
+	ORDER BY user_id, revision DESC
+`
+
+// GetAllSubmissionsBySchedulePlanID 获取每个用户当前生效的提交记录，用于排班
+// 逾期提交只有在被批准之后才会生效，在此之前仍然使用该用户上一次生效的提交
 func (r *Repository) GetAllSubmissionsBySchedulePlanID(schedulePlanID int64) ([]*domain.AvailabilitySubmission, error) {
+	where := `asm.id IN (SELECT id FROM (` + effectiveSubmissionsQuery + `) effective)`
+	return r.queryAvailabilitySubmissions(where, schedulePlanID)
+}
+
+// GetLatestSubmissionsBySchedulePlanID 获取每个用户最新的提交记录，包括还没有审批的逾期提交
+func (r *Repository) GetLatestSubmissionsBySchedulePlanID(schedulePlanID int64) ([]*domain.AvailabilitySubmission, error) {
+	return r.queryAvailabilitySubmissions(`asm.schedule_plan_id = $1 AND asm.superseded_at IS NULL`, schedulePlanID)
+}
+
+// ReviewLateSubmission 审批逾期提交
+func (r *Repository) ReviewLateSubmission(submission *domain.AvailabilitySubmission) error {
+	query := `
+		UPDATE availability_submissions
+		SET
+			late_status = $1,
+			late_reviewer_id = $2,
+			version = version + 1
+		WHERE id = $3 AND version = $4
+		RETURNING version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	params := []any{submission.LateStatus, submission.LateReviewerID, submission.ID, submission.Version}
+	if err := r.dbpool.QueryRowContext(ctx, query, params...).Scan(&submission.Version); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAvailabilitySubmissionHistory 获取某个用户在排班计划中的所有提交记录，按照提交的先后排列
+func (r *Repository) GetAvailabilitySubmissionHistory(userID int64, schedulePlanID int64) ([]*domain.AvailabilitySubmission, error) {
+	return r.queryAvailabilitySubmissions(`asm.user_id = $1 AND asm.schedule_plan_id = $2`, userID, schedulePlanID)
+}
+
+func (r *Repository) queryAvailabilitySubmissions(where string, args ...any) ([]*domain.AvailabilitySubmission, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
 	defer cancel()
 
 	query := `
 		SELECT 
 			asm.id,
+			asm.schedule_plan_id,
 			asm.user_id,
 			asmi.id,
 			asmi.schedule_template_shift_id,
 			asmiad.day_of_week,
+			asm.revision,
+			asm.superseded_at,
+			asm.late_status,
+			asm.late_reviewer_id,
 			asm.created_at,
 			asm.version
 		FROM availability_submissions asm
 		LEFT JOIN availability_submission_items asmi ON asm.id = asmi.availability_submission_id
 		LEFT JOIN availability_submission_item_available_days asmiad ON asmi.id = asmiad.availability_submission_item_id
-		WHERE asm.schedule_plan_id = $1
+		WHERE ` + where + `
+		ORDER BY asm.user_id, asm.revision, asmi.schedule_template_shift_id, asmiad.day_of_week
 	`
 
-	rows, err := r.dbpool.QueryContext(ctx, query, schedulePlanID)
+	rows, err := r.dbpool.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	submissions := make([]*domain.AvailabilitySubmission, 0)
 	submissionsMap := make(map[int64]*domain.AvailabilitySubmission)
 	itemsMap := make(map[int64]map[int64]*domain.AvailabilitySubmissionItem) // submissionID -> itemID -> item
+	itemIDs := make(map[int64][]int64)                                       // submissionID -> 按顺序排列的 itemID
 
 	for rows.Next() {
 		var row struct {
-			submissionID int64
-			userID       int64
-			itemID       sql.NullInt64
-			shiftID      sql.NullInt64
-			day          sql.NullInt32
-			createdAt    time.Time
-			version      int32
+			submissionID   int64
+			schedulePlanID int64
+			userID         int64
+			itemID         sql.NullInt64
+			shiftID        sql.NullInt64
+			day            sql.NullInt32
+			revision       int32
+			supersededAt   *time.Time
+			lateStatus     *domain.LateSubmissionStatus
+			lateReviewerID *int64
+			createdAt      time.Time
+			version        int32
 		}
 
 		dst := []any{
 			&row.submissionID,
+			&row.schedulePlanID,
 			&row.userID,
 			&row.itemID,
 			&row.shiftID,
 			&row.day,
+			&row.revision,
+			&row.supersededAt,
+			&row.lateStatus,
+			&row.lateReviewerID,
 			&row.createdAt,
 			&row.version,
 		}
@@ -191,13 +298,19 @@ func (r *Repository) GetAllSubmissionsBySchedulePlanID(schedulePlanID int64) ([]
 		}
 
 		if _, exists := submissionsMap[row.submissionID]; !exists {
-			submissionsMap[row.submissionID] = &domain.AvailabilitySubmission{
+			submission := &domain.AvailabilitySubmission{
 				ID:             row.submissionID,
-				SchedulePlanID: schedulePlanID,
+				SchedulePlanID: row.schedulePlanID,
 				UserID:         row.userID,
+				Revision:       row.revision,
+				SupersededAt:   row.supersededAt,
+				LateStatus:     row.lateStatus,
+				LateReviewerID: row.lateReviewerID,
 				CreatedAt:      row.createdAt,
 				Version:        row.version,
 			}
+			submissionsMap[row.submissionID] = submission
+			submissions = append(submissions, submission)
 			itemsMap[row.submissionID] = make(map[int64]*domain.AvailabilitySubmissionItem)
 		}
 
@@ -212,6 +325,7 @@ func (r *Repository) GetAllSubmissionsBySchedulePlanID(schedulePlanID int64) ([]
 				ShiftID: row.shiftID.Int64,
 				Days:    make([]int32, 0),
 			}
+			itemIDs[row.submissionID] = append(itemIDs[row.submissionID], row.itemID.Int64)
 		}
 
 		if !row.day.Valid {
@@ -227,17 +341,12 @@ func (r *Repository) GetAllSubmissionsBySchedulePlanID(schedulePlanID int64) ([]
 	}
 
 	// 组装结果
-	for submissionID, submission := range submissionsMap {
-		submission.Items = make([]domain.AvailabilitySubmissionItem, 0, len(itemsMap[submissionID]))
-		for _, item := range itemsMap[submissionID] {
-			submission.Items = append(submission.Items, *item)
+	for _, submission := range submissions {
+		submission.Items = make([]domain.AvailabilitySubmissionItem, 0, len(itemIDs[submission.ID]))
+		for _, itemID := range itemIDs[submission.ID] {
+			submission.Items = append(submission.Items, *itemsMap[submission.ID][itemID])
 		}
 	}
 
-	submissions := make([]*domain.AvailabilitySubmission, 0, len(submissionsMap))
-	for _, submission := range submissionsMap {
-		submissions = append(submissions, submission)
-	}
-
 	return submissions, nil
 }
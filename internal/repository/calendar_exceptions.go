@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+const calendarExceptionColumns = `
+	id,
+	schedule_plan_id,
+	date,
+	type,
+	work_as_day,
+	note,
+	created_at,
+	version
+`
+
+func calendarExceptionDst(exception *domain.CalendarException) []any {
+	return []any{
+		&exception.ID,
+		&exception.SchedulePlanID,
+		&exception.Date,
+		&exception.Type,
+		&exception.WorkAsDay,
+		&exception.Note,
+		&exception.CreatedAt,
+		&exception.Version,
+	}
+}
+
+func (r *Repository) queryCalendarExceptions(query string, args ...any) ([]*domain.CalendarException, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := r.dbpool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exceptions := make([]*domain.CalendarException, 0)
+	for rows.Next() {
+		exception := &domain.CalendarException{}
+		if err := rows.Scan(calendarExceptionDst(exception)...); err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, exception)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exceptions, nil
+}
+
+func (r *Repository) GetCalendarExceptionsBySchedulePlanID(schedulePlanID int64) ([]*domain.CalendarException, error) {
+	query := `SELECT ` + calendarExceptionColumns + ` FROM calendar_exceptions WHERE schedule_plan_id = $1 ORDER BY date`
+	return r.queryCalendarExceptions(query, schedulePlanID)
+}
+
+// GetCalendarExceptionsByDateRange 获取 [from, to] 之间所有排班计划的例外日期
+func (r *Repository) GetCalendarExceptionsByDateRange(from time.Time, to time.Time) ([]*domain.CalendarException, error) {
+	query := `SELECT ` + calendarExceptionColumns + ` FROM calendar_exceptions WHERE date BETWEEN $1 AND $2 ORDER BY date, schedule_plan_id`
+	return r.queryCalendarExceptions(query, from, to)
+}
+
+func (r *Repository) GetCalendarExceptionByID(id int64) (*domain.CalendarException, error) {
+	query := `SELECT ` + calendarExceptionColumns + ` FROM calendar_exceptions WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	exception := &domain.CalendarException{}
+	if err := r.dbpool.QueryRowContext(ctx, query, id).Scan(calendarExceptionDst(exception)...); err != nil {
+		return nil, err
+	}
+
+	return exception, nil
+}
+
+func (r *Repository) CreateCalendarException(exception *domain.CalendarException) error {
+	query := `
+		INSERT INTO calendar_exceptions (schedule_plan_id, date, type, work_as_day, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	params := []any{exception.SchedulePlanID, exception.Date, exception.Type, exception.WorkAsDay, exception.Note}
+	if err := r.dbpool.QueryRowContext(ctx, query, params...).Scan(&exception.ID, &exception.CreatedAt, &exception.Version); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) UpdateCalendarException(exception *domain.CalendarException) error {
+	query := `
+		UPDATE calendar_exceptions
+		SET
+			date = $1,
+			type = $2,
+			work_as_day = $3,
+			note = $4,
+			version = version + 1
+		WHERE id = $5 AND version = $6
+		RETURNING version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	params := []any{exception.Date, exception.Type, exception.WorkAsDay, exception.Note, exception.ID, exception.Version}
+	if err := r.dbpool.QueryRowContext(ctx, query, params...).Scan(&exception.Version); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteCalendarException(id int64) error {
+	query := `DELETE FROM calendar_exceptions WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	if _, err := r.dbpool.ExecContext(ctx, query, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpsertCalendarExceptions 在同一个事务中批量写入例外日期，已经存在的日期会被覆盖
+func (r *Repository) UpsertCalendarExceptions(exceptions []*domain.CalendarException) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := r.dbpool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, exception := range exceptions {
+		query := `
+			INSERT INTO calendar_exceptions (schedule_plan_id, date, type, work_as_day, note)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (schedule_plan_id, date) DO UPDATE
+			SET
+				type = EXCLUDED.type,
+				work_as_day = EXCLUDED.work_as_day,
+				note = EXCLUDED.note,
+				version = calendar_exceptions.version + 1
+			RETURNING id, created_at, version
+		`
+		params := []any{exception.SchedulePlanID, exception.Date, exception.Type, exception.WorkAsDay, exception.Note}
+		if err := tx.QueryRowContext(ctx, query, params...).Scan(&exception.ID, &exception.CreatedAt, &exception.Version); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+const leaveRequestColumns = `
+	id,
+	schedule_plan_id,
+	user_id,
+	schedule_template_shift_id,
+	date,
+	reason,
+	status,
+	reviewer_id,
+	substitute_id,
+	created_at,
+	updated_at,
+	version
+`
+
+func leaveRequestDst(leave *domain.LeaveRequest) []any {
+	return []any{
+		&leave.ID,
+		&leave.SchedulePlanID,
+		&leave.UserID,
+		&leave.ShiftID,
+		&leave.Date,
+		&leave.Reason,
+		&leave.Status,
+		&leave.ReviewerID,
+		&leave.SubstituteID,
+		&leave.CreatedAt,
+		&leave.UpdatedAt,
+		&leave.Version,
+	}
+}
+
+func (r *Repository) CreateLeaveRequest(leave *domain.LeaveRequest) error {
+	query := `
+		INSERT INTO leave_requests (
+			schedule_plan_id,
+			user_id,
+			schedule_template_shift_id,
+			date,
+			reason
+		) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at, updated_at, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	params := []any{leave.SchedulePlanID, leave.UserID, leave.ShiftID, leave.Date, leave.Reason}
+	dst := []any{&leave.ID, &leave.Status, &leave.CreatedAt, &leave.UpdatedAt, &leave.Version}
+	if err := r.dbpool.QueryRowContext(ctx, query, params...).Scan(dst...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) GetLeaveRequestByID(id int64) (*domain.LeaveRequest, error) {
+	query := `SELECT ` + leaveRequestColumns + ` FROM leave_requests WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	leave := &domain.LeaveRequest{}
+	if err := r.dbpool.QueryRowContext(ctx, query, id).Scan(leaveRequestDst(leave)...); err != nil {
+		return nil, err
+	}
+
+	return leave, nil
+}
+
+func (r *Repository) GetLeaveRequestsBySchedulePlanID(schedulePlanID int64) ([]*domain.LeaveRequest, error) {
+	query := `SELECT ` + leaveRequestColumns + ` FROM leave_requests WHERE schedule_plan_id = $1 ORDER BY date DESC, created_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := r.dbpool.QueryContext(ctx, query, schedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	leaves := make([]*domain.LeaveRequest, 0)
+	for rows.Next() {
+		leave := &domain.LeaveRequest{}
+		if err := rows.Scan(leaveRequestDst(leave)...); err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, leave)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return leaves, nil
+}
+
+// UpdateLeaveRequest 更新请假申请的状态、审批人以及代班人
+func (r *Repository) UpdateLeaveRequest(leave *domain.LeaveRequest) error {
+	query := `
+		UPDATE leave_requests
+		SET
+			status = $1,
+			reviewer_id = $2,
+			substitute_id = $3,
+			updated_at = NOW(),
+			version = version + 1
+		WHERE id = $4 AND version = $5
+		RETURNING updated_at, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	params := []any{leave.Status, leave.ReviewerID, leave.SubstituteID, leave.ID, leave.Version}
+	if err := r.dbpool.QueryRowContext(ctx, query, params...).Scan(&leave.UpdatedAt, &leave.Version); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// GetSchedulePlanParticipants 获取排班计划实际可以参与的在职用户，以及排班计划是否设置了参与名单
+func (r *Repository) GetSchedulePlanParticipants(schedulePlanID int64) (*domain.SchedulePlanParticipants, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	participants := &domain.SchedulePlanParticipants{
+		SchedulePlanID: schedulePlanID,
+		Users:          make([]*domain.User, 0),
+	}
+
+	query := `SELECT EXISTS (SELECT 1 FROM schedule_plan_participants WHERE schedule_plan_id = $1)`
+	if err := r.dbpool.QueryRowContext(ctx, query, schedulePlanID).Scan(&participants.Restricted); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT u.id, u.username, u.password_hash, u.full_name, u.email, u.role, u.is_active, u.created_at, u.version
+		FROM users u
+		INNER JOIN schedule_plan_effective_participants p ON p.user_id = u.id
+		WHERE p.schedule_plan_id = $1
+		ORDER BY u.id
+	`
+	rows, err := r.dbpool.QueryContext(ctx, query, schedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := &domain.User{}
+		dst := []any{&user.ID, &user.Username, &user.PasswordHash, &user.FullName, &user.Email, &user.Role, &user.IsActive, &user.CreatedAt, &user.Version}
+		if err := rows.Scan(dst...); err != nil {
+			return nil, err
+		}
+		participants.Users = append(participants.Users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return participants, nil
+}
+
+// IsSchedulePlanParticipant 判断用户是否可以参与排班计划
+func (r *Repository) IsSchedulePlanParticipant(schedulePlanID int64, userID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM schedule_plan_effective_participants
+			WHERE schedule_plan_id = $1 AND user_id = $2
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var exists bool
+	if err := r.dbpool.QueryRowContext(ctx, query, schedulePlanID, userID).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// SetSchedulePlanParticipants 用 userIDs 替换排班计划的参与名单，userIDs 为空时表示取消名单，所有在职用户都可以参与
+func (r *Repository) SetSchedulePlanParticipants(schedulePlanID int64, userIDs []int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := r.dbpool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schedule_plan_participants WHERE schedule_plan_id = $1`, schedulePlanID); err != nil {
+		return err
+	}
+
+	if len(userIDs) > 0 {
+		query := `
+			INSERT INTO schedule_plan_participants (schedule_plan_id, user_id)
+			SELECT $1, user_id FROM UNNEST($2::BIGINT[]) AS user_id
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.ExecContext(ctx, query, schedulePlanID, userIDs); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// copySchedulePlanParticipants 在事务中将排班计划 fromID 的参与名单复制到排班计划 toID，原排班计划没有设置名单时什么也不做
+func copySchedulePlanParticipants(ctx context.Context, tx *sql.Tx, fromID int64, toID int64) error {
+	query := `
+		INSERT INTO schedule_plan_participants (schedule_plan_id, user_id)
+		SELECT $2, user_id FROM schedule_plan_participants WHERE schedule_plan_id = $1
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, fromID, toID); err != nil {
+		return err
+	}
+
+	return nil
+}
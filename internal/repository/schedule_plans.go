@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
@@ -18,6 +19,8 @@ func (r *Repository) GetAllSchedulePlans() ([]*domain.SchedulePlan, error) {
 			active_start_time, 
 			active_end_time,
 			schedule_template_id,
+			rotation_start_date,
+			status,
 			created_at, 
 			version
 		FROM schedule_plans
@@ -44,6 +47,8 @@ func (r *Repository) GetAllSchedulePlans() ([]*domain.SchedulePlan, error) {
 			&plan.ActiveStartTime,
 			&plan.ActiveEndTime,
 			&plan.ScheduleTemplateID,
+			&plan.RotationStartDate,
+			&plan.Status,
 			&plan.CreatedAt,
 			&plan.Version,
 		}
@@ -71,8 +76,10 @@ func (r *Repository) UpdateSchedulePlan(plan *domain.SchedulePlan) error {
 			submission_end_time = $4,
 			active_start_time = $5,
 			active_end_time = $6,
+			rotation_start_date = $7,
+			status = $8,
 			version = version + 1
-		WHERE id = $7 AND version = $8
+		WHERE id = $9 AND version = $10
 		RETURNING version
 	`
 
@@ -86,6 +93,8 @@ func (r *Repository) UpdateSchedulePlan(plan *domain.SchedulePlan) error {
 		plan.SubmissionEndTime,
 		plan.ActiveStartTime,
 		plan.ActiveEndTime,
+		plan.RotationStartDate,
+		plan.Status,
 		plan.ID,
 		plan.Version,
 	}
@@ -97,7 +106,74 @@ func (r *Repository) UpdateSchedulePlan(plan *domain.SchedulePlan) error {
 	return nil
 }
 
+// CreateSchedulePlan 创建排班计划，同时冻结所使用模板的快照，plan.ScheduleTemplateID 会被替换为快照的 ID
+// 如果模板不存在则返回 sql.ErrNoRows
 func (r *Repository) CreateSchedulePlan(plan *domain.SchedulePlan) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := r.dbpool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := createSchedulePlan(ctx, tx, plan); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DuplicateSchedulePlan 在同一个事务中以 plan 创建排班计划，并复制排班计划 fromID 的提交要求，
+// copyParticipants 为 true 时还会复制参与名单，任意一步失败都不会留下复制了一半的排班计划
+// 返回无法对应到新模板、因此没有复制的必须提交的班次数；如果模板不存在则返回 sql.ErrNoRows
+func (r *Repository) DuplicateSchedulePlan(fromID int64, plan *domain.SchedulePlan, copyParticipants bool) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := r.dbpool.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := createSchedulePlan(ctx, tx, plan); err != nil {
+		return 0, err
+	}
+
+	unmapped, err := copySubmissionRules(ctx, tx, fromID, plan.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if copyParticipants {
+		if err := copySchedulePlanParticipants(ctx, tx, fromID, plan.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return unmapped, nil
+}
+
+func createSchedulePlan(ctx context.Context, tx *sql.Tx, plan *domain.SchedulePlan) error {
+	snapshotID, err := snapshotScheduleTemplate(ctx, tx, plan.ScheduleTemplateID)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO schedule_plans (
 			name,
@@ -106,14 +182,12 @@ func (r *Repository) CreateSchedulePlan(plan *domain.SchedulePlan) error {
 			submission_end_time,
 			active_start_time,
 			active_end_time,
-			schedule_template_id
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, created_at, version
+			schedule_template_id,
+			rotation_start_date
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, status, created_at, version
 	`
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
-	defer cancel()
-
 	params := []any{
 		plan.Name,
 		plan.Description,
@@ -121,13 +195,15 @@ func (r *Repository) CreateSchedulePlan(plan *domain.SchedulePlan) error {
 		plan.SubmissionEndTime,
 		plan.ActiveStartTime,
 		plan.ActiveEndTime,
-		plan.ScheduleTemplateID,
+		snapshotID,
+		plan.RotationStartDate,
 	}
-	dst := []any{&plan.ID, &plan.CreatedAt, &plan.Version}
-	if err := r.dbpool.QueryRowContext(ctx, query, params...).Scan(dst...); err != nil {
+	dst := []any{&plan.ID, &plan.Status, &plan.CreatedAt, &plan.Version}
+	if err := tx.QueryRowContext(ctx, query, params...).Scan(dst...); err != nil {
 		return err
 	}
 
+	plan.ScheduleTemplateID = snapshotID
 	return nil
 }
 
@@ -141,6 +217,8 @@ func (r *Repository) GetSchedulePlanByID(id int64) (*domain.SchedulePlan, error)
 			active_start_time, 
 			active_end_time, 
 			schedule_template_id,
+			rotation_start_date,
+			status,
 			created_at, 
 			version
 		FROM schedule_plans
@@ -162,6 +240,8 @@ func (r *Repository) GetSchedulePlanByID(id int64) (*domain.SchedulePlan, error)
 		&plan.ActiveStartTime,
 		&plan.ActiveEndTime,
 		&plan.ScheduleTemplateID,
+		&plan.RotationStartDate,
+		&plan.Status,
 		&plan.CreatedAt,
 		&plan.Version,
 	}
@@ -193,7 +273,7 @@ func (r *Repository) GetLatestAvailableSchedulePlanID() (int64, error) {
 	defer cancel()
 
 	query := `
-		SELECT id FROM schedule_plans WHERE submission_end_time > NOW()
+		SELECT id FROM schedule_plans WHERE status = '收集中'
 		ORDER BY created_at DESC
 		LIMIT 1
 	`
@@ -205,3 +285,78 @@ func (r *Repository) GetLatestAvailableSchedulePlanID() (int64, error) {
 
 	return id, nil
 }
+
+// UpdateSchedulePlanStatus 更新排班计划的状态，状态转换是否合法由调用方负责检查
+func (r *Repository) UpdateSchedulePlanStatus(plan *domain.SchedulePlan) error {
+	query := `
+		UPDATE schedule_plans
+		SET
+			status = $1,
+			version = version + 1
+		WHERE id = $2 AND version = $3
+		RETURNING version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	if err := r.dbpool.QueryRowContext(ctx, query, plan.Status, plan.ID, plan.Version).Scan(&plan.Version); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AdvanceSchedulePlanStatuses 根据当前时间自动推进排班计划的状态，返回状态发生变化的排班计划 ID
+// 只处理由时间决定的转换，排班和发布需要黑心手动操作
+func (r *Repository) AdvanceSchedulePlanStatuses(now time.Time) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := r.dbpool.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// 按照先后顺序执行，使得错过了多个时间点的排班计划也能一次推进到位
+	queries := []string{
+		`UPDATE schedule_plans SET status = '收集中', version = version + 1
+		WHERE status = '草稿' AND submission_start_time <= $1 RETURNING id`,
+		`UPDATE schedule_plans SET status = '已截止', version = version + 1
+		WHERE status = '收集中' AND submission_end_time <= $1 RETURNING id`,
+		`UPDATE schedule_plans SET status = '生效中', version = version + 1
+		WHERE status = '已发布' AND active_start_time <= $1 RETURNING id`,
+		`UPDATE schedule_plans SET status = '已归档', version = version + 1
+		WHERE status = '生效中' AND active_end_time <= $1 RETURNING id`,
+	}
+
+	ids := make([]int64, 0)
+	for _, query := range queries {
+		rows, err := tx.QueryContext(ctx, query, now)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rows.Close()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
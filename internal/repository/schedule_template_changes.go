@@ -0,0 +1,378 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
+)
+
+// ErrScheduleTemplateShiftHasRecords 被删除的班次上已经有考勤或者请假记录
+var ErrScheduleTemplateShiftHasRecords = errors.New("被删除的班次上已经有考勤或者请假记录，无法删除")
+
+// GetScheduleTemplateImpacts 分析修改模板班次对每个使用该模板的排班计划的影响，只统计不修改
+func (r *Repository) GetScheduleTemplateImpacts(templateID int64, changes *domain.ScheduleTemplateChanges) ([]*domain.ScheduleTemplateImpact, error) {
+	removedShiftIDs, removedDays := utils.RemovedScheduleTemplateSlots(changes)
+	changedShiftIDs := utils.ChangedScheduleTemplateShiftIDs(changes)
+	deletedShiftIDs := make([]int64, 0, len(changes.Removed))
+	for _, shift := range changes.Removed {
+		deletedShiftIDs = append(deletedShiftIDs, shift.ID)
+	}
+
+	// 修改后每个 (shift, day) 需要的人数，用于统计超出人数要求的排班结果
+	requiredShiftIDs := make([]int64, 0)
+	requiredDays := make([]int32, 0)
+	requiredNumbers := make([]int32, 0)
+	principalRequired := make([]bool, 0)
+	for _, change := range changes.Modified {
+		for _, day := range change.After.ApplicableDays {
+			number, principal := change.After.RequirementOn(day)
+			requiredShiftIDs = append(requiredShiftIDs, change.After.ID)
+			requiredDays = append(requiredDays, day)
+			requiredNumbers = append(requiredNumbers, number)
+			principalRequired = append(principalRequired, principal)
+		}
+	}
+
+	query := `
+		WITH removed AS (
+			SELECT * FROM UNNEST($2::BIGINT[], $3::INT[]) AS t(shift_id, day)
+		), required AS (
+			SELECT * FROM UNNEST($5::BIGINT[], $6::INT[], $7::INT[], $8::BOOLEAN[]) AS t(shift_id, day, number, principal_required)
+		)
+		SELECT
+			p.id,
+			p.name,
+			p.status,
+			(
+				SELECT COUNT(DISTINCT s.user_id)
+				FROM availability_submissions s
+				INNER JOIN availability_submission_items asi ON asi.availability_submission_id = s.id
+				INNER JOIN availability_submission_item_available_days asd ON asd.availability_submission_item_id = asi.id
+				WHERE s.schedule_plan_id = p.id AND s.superseded_at IS NULL AND asi.schedule_template_shift_id = ANY($4)
+			),
+			(
+				SELECT COUNT(*)
+				FROM availability_submissions s
+				INNER JOIN availability_submission_items asi ON asi.availability_submission_id = s.id
+				INNER JOIN availability_submission_item_available_days asd ON asd.availability_submission_item_id = asi.id
+				INNER JOIN removed ON removed.shift_id = asi.schedule_template_shift_id AND removed.day = asd.day_of_week
+				WHERE s.schedule_plan_id = p.id AND s.superseded_at IS NULL
+			),
+			(
+				SELECT COUNT(*)
+				FROM scheduling_results sr
+				INNER JOIN scheduling_result_shifts srs ON srs.scheduling_result_id = sr.id
+				INNER JOIN scheduling_result_shift_items srsi ON srsi.scheduling_result_shift_id = srs.id
+				WHERE sr.schedule_plan_id = p.id AND srs.schedule_template_shift_id = ANY($4)
+					AND (srsi.principal_id IS NOT NULL OR EXISTS (
+						SELECT 1 FROM scheduling_result_shift_item_assistants a WHERE a.scheduling_result_shift_item_id = srsi.id
+					))
+			),
+			(
+				SELECT COUNT(*)
+				FROM scheduling_results sr
+				INNER JOIN scheduling_result_shifts srs ON srs.scheduling_result_id = sr.id
+				INNER JOIN scheduling_result_shift_items srsi ON srsi.scheduling_result_shift_id = srs.id
+				INNER JOIN removed ON removed.shift_id = srs.schedule_template_shift_id AND removed.day = srsi.day_of_week
+				WHERE sr.schedule_plan_id = p.id
+					AND (srsi.principal_id IS NOT NULL OR EXISTS (
+						SELECT 1 FROM scheduling_result_shift_item_assistants a WHERE a.scheduling_result_shift_item_id = srsi.id
+					))
+			),
+			(
+				SELECT COUNT(*)
+				FROM scheduling_results sr
+				INNER JOIN scheduling_result_shifts srs ON srs.scheduling_result_id = sr.id
+				INNER JOIN scheduling_result_shift_items srsi ON srsi.scheduling_result_shift_id = srs.id
+				INNER JOIN required ON required.shift_id = srs.schedule_template_shift_id AND required.day = srsi.day_of_week
+				WHERE sr.schedule_plan_id = p.id
+					AND (
+						SELECT COUNT(*) FROM scheduling_result_shift_item_assistants a WHERE a.scheduling_result_shift_item_id = srsi.id
+					) + CASE WHEN required.principal_required OR srsi.principal_id IS NOT NULL THEN 1 ELSE 0 END > required.number
+			),
+			(
+				SELECT COUNT(*)
+				FROM shift_swap_requests sw
+				WHERE sw.schedule_plan_id = p.id AND sw.status IN ('待接受', '待审批')
+					AND EXISTS (
+						SELECT 1 FROM removed
+						WHERE (removed.shift_id = sw.schedule_template_shift_id AND removed.day = sw.day_of_week)
+							OR (removed.shift_id = sw.counterparty_schedule_template_shift_id AND removed.day = sw.counterparty_day_of_week)
+					)
+			),
+			(
+				SELECT COUNT(*)
+				FROM leave_requests l
+				WHERE l.schedule_plan_id = p.id AND l.status IN ('待审批', '已批准') AND l.schedule_template_shift_id = ANY($4)
+			),
+			(
+				SELECT COUNT(*)
+				FROM shift_instances si
+				WHERE si.schedule_plan_id = p.id AND si.schedule_template_shift_id = ANY($4)
+			),
+			(
+				SELECT COUNT(*)
+				FROM shift_instances si
+				WHERE si.schedule_plan_id = p.id AND si.schedule_template_shift_id = ANY($9)
+					AND (si.check_in_at IS NOT NULL OR si.check_out_at IS NOT NULL OR si.status_override IS NOT NULL)
+			),
+			(
+				SELECT COUNT(*)
+				FROM leave_requests l
+				WHERE l.schedule_plan_id = p.id AND l.schedule_template_shift_id = ANY($9)
+			)
+		FROM schedule_plans p
+		WHERE p.schedule_template_id = $1
+		ORDER BY p.id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	params := []any{templateID, removedShiftIDs, removedDays, changedShiftIDs, requiredShiftIDs, requiredDays, requiredNumbers, principalRequired, deletedShiftIDs}
+	rows, err := r.dbpool.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	impacts := make([]*domain.ScheduleTemplateImpact, 0)
+	for rows.Next() {
+		impact := &domain.ScheduleTemplateImpact{}
+		dst := []any{
+			&impact.SchedulePlanID,
+			&impact.SchedulePlanName,
+			&impact.Status,
+			&impact.AffectedSubmissions,
+			&impact.DroppedSubmissionSlots,
+			&impact.AffectedResultItems,
+			&impact.DroppedResultItems,
+			&impact.OverstaffedResultItems,
+			&impact.PendingShiftSwaps,
+			&impact.LeaveRequests,
+			&impact.ShiftInstances,
+			&impact.RemovedShiftAttendance,
+			&impact.RemovedShiftLeaves,
+		}
+		if err := rows.Scan(dst...); err != nil {
+			return nil, err
+		}
+		impacts = append(impacts, impact)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return impacts, nil
+}
+
+// MigrateScheduleTemplateShifts 在同一个事务中原地修改模板的班次，并迁移所有使用该模板的排班计划中依赖这些班次的数据：
+//   - 被删除的班次：依赖它的提交、排班结果、换班和还没有考勤记录的具体值班都会被删除；
+//     如果班次上已经有考勤或者请假记录则返回 ErrScheduleTemplateShiftHasRecords，这些记录不能随着班次一起删除
+//   - 被修改的班次：ID 不变，不再开放的天数上的提交和排班结果会被删除，涉及这些天数且还没有处理完的换班申请会被取消，
+//     新开放的天数会在排班结果中补上空的安排
+//   - 新增的班次：在已有的提交中补上空的选择，在已有的排班结果中补上空的安排
+//
+// 模板通过 version 做乐观锁，如果在此期间模板被修改过则返回 sql.ErrNoRows
+func (r *Repository) MigrateScheduleTemplateShifts(template *domain.ScheduleTemplate, changes *domain.ScheduleTemplateChanges) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := r.dbpool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := `
+		UPDATE schedule_templates
+		SET version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING version
+	`
+	if err := tx.QueryRowContext(ctx, query, template.ID, template.Version).Scan(&template.Version); err != nil {
+		return err
+	}
+
+	for _, shift := range changes.Removed {
+		query := `
+			SELECT
+				EXISTS (
+					SELECT 1 FROM shift_instances
+					WHERE schedule_template_shift_id = $1
+						AND (check_in_at IS NOT NULL OR check_out_at IS NOT NULL OR status_override IS NOT NULL)
+				)
+				OR EXISTS (SELECT 1 FROM leave_requests WHERE schedule_template_shift_id = $1)
+		`
+		var hasRecords bool
+		if err := tx.QueryRowContext(ctx, query, shift.ID).Scan(&hasRecords); err != nil {
+			return err
+		}
+		if hasRecords {
+			return ErrScheduleTemplateShiftHasRecords
+		}
+
+		// 依赖班次的数据都需要先显式删除，不能依赖级联删除
+		queries := []string{
+			`DELETE FROM availability_submission_items WHERE schedule_template_shift_id = $1`,
+			`DELETE FROM scheduling_result_shifts WHERE schedule_template_shift_id = $1`,
+			`DELETE FROM shift_swap_requests WHERE schedule_template_shift_id = $1 OR counterparty_schedule_template_shift_id = $1`,
+			`DELETE FROM shift_instances WHERE schedule_template_shift_id = $1`,
+			`DELETE FROM schedule_template_shifts WHERE id = $1`,
+		}
+		for _, query := range queries {
+			if _, err := tx.ExecContext(ctx, query, shift.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, change := range changes.Modified {
+		shift := change.After
+
+		query := `
+			UPDATE schedule_template_shifts
+			SET start_time = $1, end_time = $2, location = $3, required_assistant_number = $4
+			WHERE id = $5
+		`
+		params := []any{shift.StartTime, shift.EndTime, shift.Location, shift.RequiredAssistantNumber, shift.ID}
+		if _, err := tx.ExecContext(ctx, query, params...); err != nil {
+			return err
+		}
+
+		// 按天的人数要求整体替换
+		query = `DELETE FROM schedule_template_shift_day_requirements WHERE shift_id = $1`
+		if _, err := tx.ExecContext(ctx, query, shift.ID); err != nil {
+			return err
+		}
+		if err := insertScheduleTemplateShiftDayRequirements(ctx, tx, shift.ID, shift.DayRequirements); err != nil {
+			return err
+		}
+
+		if len(change.RemovedDays) > 0 {
+			queries := []string{
+				`DELETE FROM schedule_template_shift_applicable_days WHERE shift_id = $1 AND day = ANY($2)`,
+				`
+					DELETE FROM availability_submission_item_available_days asd
+					USING availability_submission_items asi
+					WHERE asd.availability_submission_item_id = asi.id
+						AND asi.schedule_template_shift_id = $1
+						AND asd.day_of_week = ANY($2)
+				`,
+				`
+					DELETE FROM scheduling_result_shift_items srsi
+					USING scheduling_result_shifts srs
+					WHERE srsi.scheduling_result_shift_id = srs.id
+						AND srs.schedule_template_shift_id = $1
+						AND srsi.day_of_week = ANY($2)
+				`,
+				`
+					UPDATE shift_swap_requests
+					SET status = '已取消', updated_at = NOW(), version = version + 1
+					WHERE status IN ('待接受', '待审批')
+						AND (
+							(schedule_template_shift_id = $1 AND day_of_week = ANY($2))
+							OR (counterparty_schedule_template_shift_id = $1 AND counterparty_day_of_week = ANY($2))
+						)
+				`,
+			}
+			for _, query := range queries {
+				if _, err := tx.ExecContext(ctx, query, shift.ID, change.RemovedDays); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(change.AddedDays) > 0 {
+			queries := []string{
+				`
+					INSERT INTO schedule_template_shift_applicable_days (shift_id, day)
+					SELECT $1, day FROM UNNEST($2::INT[]) AS day
+				`,
+				`
+					INSERT INTO scheduling_result_shift_items (scheduling_result_shift_id, day_of_week)
+					SELECT srs.id, day
+					FROM scheduling_result_shifts srs, UNNEST($2::INT[]) AS day
+					WHERE srs.schedule_template_shift_id = $1
+				`,
+			}
+			for _, query := range queries {
+				if _, err := tx.ExecContext(ctx, query, shift.ID, change.AddedDays); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for i := range changes.Added {
+		shift := &changes.Added[i]
+
+		query := `
+			INSERT INTO schedule_template_shifts (template_id, start_time, end_time, location, required_assistant_number)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`
+		params := []any{template.ID, shift.StartTime, shift.EndTime, shift.Location, shift.RequiredAssistantNumber}
+		if err := tx.QueryRowContext(ctx, query, params...).Scan(&shift.ID); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO schedule_template_shift_applicable_days (shift_id, day)
+			SELECT $1, day FROM UNNEST($2::INT[]) AS day
+		`
+		if _, err := tx.ExecContext(ctx, query, shift.ID, shift.ApplicableDays); err != nil {
+			return err
+		}
+
+		if err := insertScheduleTemplateShiftDayRequirements(ctx, tx, shift.ID, shift.DayRequirements); err != nil {
+			return err
+		}
+
+		// 已有的提交中补上空的选择
+		query = `
+			INSERT INTO availability_submission_items (availability_submission_id, schedule_template_shift_id)
+			SELECT s.id, $2
+			FROM availability_submissions s
+			INNER JOIN schedule_plans p ON p.id = s.schedule_plan_id
+			WHERE p.schedule_template_id = $1
+		`
+		if _, err := tx.ExecContext(ctx, query, template.ID, shift.ID); err != nil {
+			return err
+		}
+
+		// 已有的排班结果中补上空的安排
+		query = `
+			INSERT INTO scheduling_result_shifts (scheduling_result_id, schedule_template_shift_id)
+			SELECT sr.id, $2
+			FROM scheduling_results sr
+			INNER JOIN schedule_plans p ON p.id = sr.schedule_plan_id
+			WHERE p.schedule_template_id = $1
+		`
+		if _, err := tx.ExecContext(ctx, query, template.ID, shift.ID); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO scheduling_result_shift_items (scheduling_result_shift_id, day_of_week)
+			SELECT srs.id, day
+			FROM scheduling_result_shifts srs, UNNEST($2::INT[]) AS day
+			WHERE srs.schedule_template_shift_id = $1
+		`
+		if _, err := tx.ExecContext(ctx, query, shift.ID, shift.ApplicableDays); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
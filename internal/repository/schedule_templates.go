@@ -17,16 +17,22 @@ func (r *Repository) GetAllScheduleTemplates() ([]*domain.ScheduleTemplate, erro
 			st.id,
 			st.name,
 			st.description,
+			st.rotation_weeks,
+			st.is_snapshot,
+			st.source_template_id,
+			st.source_version,
 			st.created_at,
 			st.version,
 			sts.id,
 			sts.start_time,
 			sts.end_time,
+			sts.location,
 			sts.required_assistant_number,
 			stsad.day
 		FROM schedule_templates st
 		LEFT JOIN schedule_template_shifts sts ON st.id = sts.template_id
 		LEFT JOIN schedule_template_shift_applicable_days stsad ON sts.id = stsad.shift_id
+		WHERE NOT st.is_snapshot
 		ORDER BY st.id, sts.id
 	`
 
@@ -41,15 +47,20 @@ func (r *Repository) GetAllScheduleTemplates() ([]*domain.ScheduleTemplate, erro
 
 	for rows.Next() {
 		var row struct {
-			ID          int64
-			Name        string
-			Description string
-			CreatedAt   time.Time
-			Version     int32
+			ID               int64
+			Name             string
+			Description      string
+			RotationWeeks    int32
+			IsSnapshot       bool
+			SourceTemplateID *int64
+			SourceVersion    *int32
+			CreatedAt        time.Time
+			Version          int32
 
 			ShiftID                 sql.NullInt64
 			StartTime               sql.NullString
 			EndTime                 sql.NullString
+			Location                sql.NullString
 			RequiredAssistantNumber sql.NullInt32
 			Day                     sql.NullInt32
 		}
@@ -58,11 +69,16 @@ func (r *Repository) GetAllScheduleTemplates() ([]*domain.ScheduleTemplate, erro
 			&row.ID,
 			&row.Name,
 			&row.Description,
+			&row.RotationWeeks,
+			&row.IsSnapshot,
+			&row.SourceTemplateID,
+			&row.SourceVersion,
 			&row.CreatedAt,
 			&row.Version,
 			&row.ShiftID,
 			&row.StartTime,
 			&row.EndTime,
+			&row.Location,
 			&row.RequiredAssistantNumber,
 			&row.Day,
 		}
@@ -73,11 +89,15 @@ func (r *Repository) GetAllScheduleTemplates() ([]*domain.ScheduleTemplate, erro
 		if _, exists := templatesMap[row.ID]; !exists {
 			// 说明此时是第一次查到这个 template，需要在 map 中初始化这个 template
 			template := &domain.ScheduleTemplate{
-				ID:          row.ID,
-				Name:        row.Name,
-				Description: row.Description,
-				CreatedAt:   row.CreatedAt,
-				Version:     row.Version,
+				ID:               row.ID,
+				Name:             row.Name,
+				Description:      row.Description,
+				RotationWeeks:    row.RotationWeeks,
+				IsSnapshot:       row.IsSnapshot,
+				SourceTemplateID: row.SourceTemplateID,
+				SourceVersion:    row.SourceVersion,
+				CreatedAt:        row.CreatedAt,
+				Version:          row.Version,
 			}
 			templatesMap[row.ID] = template
 			shiftsMap[row.ID] = make(map[int64]*domain.ScheduleTemplateShift)
@@ -96,8 +116,10 @@ func (r *Repository) GetAllScheduleTemplates() ([]*domain.ScheduleTemplate, erro
 				ID:                      row.ShiftID.Int64,
 				StartTime:               row.StartTime.String,
 				EndTime:                 row.EndTime.String,
+				Location:                row.Location.String,
 				RequiredAssistantNumber: row.RequiredAssistantNumber.Int32,
 				ApplicableDays:          make([]int32, 0),
+				DayRequirements:         make([]domain.ScheduleTemplateShiftDayRequirement, 0),
 			}
 			shiftsMap[row.ID][row.ShiftID.Int64] = shift
 		}
@@ -115,6 +137,19 @@ func (r *Repository) GetAllScheduleTemplates() ([]*domain.ScheduleTemplate, erro
 		return nil, err
 	}
 
+	rows.Close()
+
+	// 查询每个班次按天的人数要求
+	allShifts := make(map[int64]*domain.ScheduleTemplateShift)
+	for _, shifts := range shiftsMap {
+		for shiftID, shift := range shifts {
+			allShifts[shiftID] = shift
+		}
+	}
+	if err := r.attachScheduleTemplateShiftDayRequirements(ctx, allShifts); err != nil {
+		return nil, err
+	}
+
 	// 组装结果
 	stms := make([]*domain.ScheduleTemplate, 0, len(templatesMap))
 
@@ -142,21 +177,21 @@ func (r *Repository) CreateScheduleTemplate(stm *domain.ScheduleTemplate) error
 	}()
 
 	query := `
-		INSERT INTO schedule_templates (name, description)
-		VALUES ($1, $2)
+		INSERT INTO schedule_templates (name, description, rotation_weeks)
+		VALUES ($1, $2, $3)
 		RETURNING id, created_at, version
 	`
-	if err := tx.QueryRowContext(ctx, query, stm.Name, stm.Description).Scan(&stm.ID, &stm.CreatedAt, &stm.Version); err != nil {
+	if err := tx.QueryRowContext(ctx, query, stm.Name, stm.Description, stm.RotationWeeks).Scan(&stm.ID, &stm.CreatedAt, &stm.Version); err != nil {
 		return err
 	}
 
 	for i := range stm.Shifts {
 		query = `
-			INSERT INTO schedule_template_shifts (template_id, start_time, end_time, required_assistant_number)
-			VALUES ($1, $2, $3, $4)
+			INSERT INTO schedule_template_shifts (template_id, start_time, end_time, location, required_assistant_number)
+			VALUES ($1, $2, $3, $4, $5)
 			RETURNING id
 		`
-		params := []any{stm.ID, stm.Shifts[i].StartTime, stm.Shifts[i].EndTime, stm.Shifts[i].RequiredAssistantNumber}
+		params := []any{stm.ID, stm.Shifts[i].StartTime, stm.Shifts[i].EndTime, stm.Shifts[i].Location, stm.Shifts[i].RequiredAssistantNumber}
 		if err := tx.QueryRowContext(ctx, query, params...).Scan(&stm.Shifts[i].ID); err != nil {
 			return err
 		}
@@ -170,6 +205,10 @@ func (r *Repository) CreateScheduleTemplate(stm *domain.ScheduleTemplate) error
 				return err
 			}
 		}
+
+		if err := insertScheduleTemplateShiftDayRequirements(ctx, tx, stm.Shifts[i].ID, stm.Shifts[i].DayRequirements); err != nil {
+			return err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -187,11 +226,16 @@ func (r *Repository) GetScheduleTemplate(id int64) (*domain.ScheduleTemplate, er
 		SELECT
 			st.name,
 			st.description,
+			st.rotation_weeks,
+			st.is_snapshot,
+			st.source_template_id,
+			st.source_version,
 			st.created_at,
 			st.version,
 			sts.id,
 			sts.start_time,
 			sts.end_time,
+			sts.location,
 			sts.required_assistant_number,
 			stsad.day
 		FROM schedule_templates st
@@ -214,14 +258,19 @@ func (r *Repository) GetScheduleTemplate(id int64) (*domain.ScheduleTemplate, er
 
 	for rows.Next() {
 		var row struct {
-			Name        string
-			Description string
-			CreatedAt   time.Time
-			Version     int32
+			Name             string
+			Description      string
+			RotationWeeks    int32
+			IsSnapshot       bool
+			SourceTemplateID *int64
+			SourceVersion    *int32
+			CreatedAt        time.Time
+			Version          int32
 
 			ShiftID                 sql.NullInt64
 			StartTime               sql.NullString
 			EndTime                 sql.NullString
+			Location                sql.NullString
 			RequiredAssistantNumber sql.NullInt32
 			Day                     sql.NullInt32
 		}
@@ -229,11 +278,16 @@ func (r *Repository) GetScheduleTemplate(id int64) (*domain.ScheduleTemplate, er
 		dst := []any{
 			&row.Name,
 			&row.Description,
+			&row.RotationWeeks,
+			&row.IsSnapshot,
+			&row.SourceTemplateID,
+			&row.SourceVersion,
 			&row.CreatedAt,
 			&row.Version,
 			&row.ShiftID,
 			&row.StartTime,
 			&row.EndTime,
+			&row.Location,
 			&row.RequiredAssistantNumber,
 			&row.Day,
 		}
@@ -245,6 +299,10 @@ func (r *Repository) GetScheduleTemplate(id int64) (*domain.ScheduleTemplate, er
 			// 说明此时是第一次查到这个模板，需要初始化这个模板
 			st.Name = row.Name
 			st.Description = row.Description
+			st.RotationWeeks = row.RotationWeeks
+			st.IsSnapshot = row.IsSnapshot
+			st.SourceTemplateID = row.SourceTemplateID
+			st.SourceVersion = row.SourceVersion
 			st.CreatedAt = row.CreatedAt
 			st.Version = row.Version
 		}
@@ -262,8 +320,10 @@ func (r *Repository) GetScheduleTemplate(id int64) (*domain.ScheduleTemplate, er
 				ID:                      row.ShiftID.Int64,
 				StartTime:               row.StartTime.String,
 				EndTime:                 row.EndTime.String,
+				Location:                row.Location.String,
 				RequiredAssistantNumber: row.RequiredAssistantNumber.Int32,
 				ApplicableDays:          make([]int32, 0),
+				DayRequirements:         make([]domain.ScheduleTemplateShiftDayRequirement, 0),
 			}
 			shiftsMap[row.ShiftID.Int64] = shift
 		}
@@ -280,6 +340,12 @@ func (r *Repository) GetScheduleTemplate(id int64) (*domain.ScheduleTemplate, er
 		return nil, err
 	}
 
+	rows.Close()
+
+	if err := r.attachScheduleTemplateShiftDayRequirements(ctx, shiftsMap); err != nil {
+		return nil, err
+	}
+
 	st.Shifts = make([]domain.ScheduleTemplateShift, 0, len(shiftsMap))
 	for _, shift := range shiftsMap {
 		st.Shifts = append(st.Shifts, *shift)
@@ -336,3 +402,132 @@ func (r *Repository) DeleteScheduleTemplate(id int64) error {
 
 	return nil
 }
+
+// snapshotScheduleTemplate 在事务中冻结模板当前的内容，返回快照的 ID
+// 快照本身不会再改变，因此如果传入的已经是快照，则直接复用
+func snapshotScheduleTemplate(ctx context.Context, tx *sql.Tx, templateID int64) (int64, error) {
+	var isSnapshot bool
+	query := `SELECT is_snapshot FROM schedule_templates WHERE id = $1 FOR SHARE`
+	if err := tx.QueryRowContext(ctx, query, templateID).Scan(&isSnapshot); err != nil {
+		return 0, err
+	}
+	if isSnapshot {
+		return templateID, nil
+	}
+
+	var snapshotID int64
+	query = `
+		INSERT INTO schedule_templates (name, description, rotation_weeks, is_snapshot, source_template_id, source_version)
+		SELECT name, description, rotation_weeks, TRUE, id, version
+		FROM schedule_templates
+		WHERE id = $1
+		RETURNING id
+	`
+	if err := tx.QueryRowContext(ctx, query, templateID).Scan(&snapshotID); err != nil {
+		return 0, err
+	}
+
+	query = `SELECT id, start_time, end_time, location, required_assistant_number FROM schedule_template_shifts WHERE template_id = $1 ORDER BY id`
+	rows, err := tx.QueryContext(ctx, query, templateID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var shifts []domain.ScheduleTemplateShift
+	for rows.Next() {
+		var shift domain.ScheduleTemplateShift
+		if err := rows.Scan(&shift.ID, &shift.StartTime, &shift.EndTime, &shift.Location, &shift.RequiredAssistantNumber); err != nil {
+			return 0, err
+		}
+		shifts = append(shifts, shift)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, shift := range shifts {
+		var shiftID int64
+		query := `
+			INSERT INTO schedule_template_shifts (template_id, start_time, end_time, location, required_assistant_number)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`
+		params := []any{snapshotID, shift.StartTime, shift.EndTime, shift.Location, shift.RequiredAssistantNumber}
+		if err := tx.QueryRowContext(ctx, query, params...).Scan(&shiftID); err != nil {
+			return 0, err
+		}
+
+		query = `
+			INSERT INTO schedule_template_shift_applicable_days (shift_id, day)
+			SELECT $1, day FROM schedule_template_shift_applicable_days WHERE shift_id = $2
+		`
+		if _, err := tx.ExecContext(ctx, query, shiftID, shift.ID); err != nil {
+			return 0, err
+		}
+
+		query = `
+			INSERT INTO schedule_template_shift_day_requirements (shift_id, day, required_assistant_number, principal_required)
+			SELECT $1, day, required_assistant_number, principal_required
+			FROM schedule_template_shift_day_requirements
+			WHERE shift_id = $2
+		`
+		if _, err := tx.ExecContext(ctx, query, shiftID, shift.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	return snapshotID, nil
+}
+
+// attachScheduleTemplateShiftDayRequirements 查询班次按天的人数要求并填入对应的班次中
+func (r *Repository) attachScheduleTemplateShiftDayRequirements(ctx context.Context, shifts map[int64]*domain.ScheduleTemplateShift) error {
+	if len(shifts) == 0 {
+		return nil
+	}
+
+	shiftIDs := make([]int64, 0, len(shifts))
+	for shiftID := range shifts {
+		shiftIDs = append(shiftIDs, shiftID)
+	}
+
+	query := `
+		SELECT shift_id, day, required_assistant_number, principal_required
+		FROM schedule_template_shift_day_requirements
+		WHERE shift_id = ANY($1)
+		ORDER BY shift_id, day
+	`
+	rows, err := r.dbpool.QueryContext(ctx, query, shiftIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shiftID int64
+		var requirement domain.ScheduleTemplateShiftDayRequirement
+		if err := rows.Scan(&shiftID, &requirement.Day, &requirement.RequiredAssistantNumber, &requirement.PrincipalRequired); err != nil {
+			return err
+		}
+		shifts[shiftID].DayRequirements = append(shifts[shiftID].DayRequirements, requirement)
+	}
+
+	return rows.Err()
+}
+
+// insertScheduleTemplateShiftDayRequirements 在事务中写入班次按天的人数要求
+func insertScheduleTemplateShiftDayRequirements(ctx context.Context, tx *sql.Tx, shiftID int64, requirements []domain.ScheduleTemplateShiftDayRequirement) error {
+	for _, requirement := range requirements {
+		query := `
+			INSERT INTO schedule_template_shift_day_requirements (shift_id, day, required_assistant_number, principal_required)
+			VALUES ($1, $2, $3, $4)
+		`
+		params := []any{shiftID, requirement.Day, requirement.RequiredAssistantNumber, requirement.PrincipalRequired}
+		if _, err := tx.ExecContext(ctx, query, params...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
 )
 
-func (r *Repository) InsertSchedulingResult(result *domain.SchedulingResult) error {
+// InsertSchedulingResult 在同一个事务中替换排班计划的排班结果，第一次提交时排班计划从已截止进入排班中
+// 排班计划通过 version 做乐观锁，如果在此期间排班计划被修改过或者已经不允许修改排班结果则返回 sql.ErrNoRows
+func (r *Repository) InsertSchedulingResult(plan *domain.SchedulePlan, result *domain.SchedulingResult) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
 	defer cancel()
 
@@ -20,8 +22,22 @@ func (r *Repository) InsertSchedulingResult(result *domain.SchedulingResult) err
 		_ = tx.Rollback()
 	}()
 
+	query := `
+		UPDATE schedule_plans
+		SET
+			status = CASE WHEN status = '已截止' THEN '排班中'::schedule_plan_status ELSE status END,
+			version = version + 1
+		WHERE id = $1 AND version = $2 AND status IN ('已截止', '排班中')
+		RETURNING status, version
+	`
+	var status domain.SchedulePlanStatus
+	var version int32
+	if err := tx.QueryRowContext(ctx, query, plan.ID, plan.Version).Scan(&status, &version); err != nil {
+		return err
+	}
+
 	// 先将之前的排班结果删除
-	query := `DELETE FROM scheduling_results WHERE schedule_plan_id = $1`
+	query = `DELETE FROM scheduling_results WHERE schedule_plan_id = $1`
 	if _, err := tx.ExecContext(ctx, query, result.SchedulePlanID); err != nil {
 		return err
 	}
@@ -77,6 +93,8 @@ func (r *Repository) InsertSchedulingResult(result *domain.SchedulingResult) err
 		return err
 	}
 
+	plan.Status = status
+	plan.Version = version
 	return nil
 }
 
@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+const shiftInstanceColumns = `
+	id,
+	schedule_plan_id,
+	schedule_template_shift_id,
+	date,
+	user_id,
+	is_principal,
+	start_at,
+	end_at,
+	check_in_at,
+	check_out_at,
+	status_override,
+	note,
+	corrected_by,
+	created_at,
+	updated_at,
+	version
+`
+
+func shiftInstanceDst(instance *domain.ShiftInstance) []any {
+	return []any{
+		&instance.ID,
+		&instance.SchedulePlanID,
+		&instance.ShiftID,
+		&instance.Date,
+		&instance.UserID,
+		&instance.IsPrincipal,
+		&instance.StartAt,
+		&instance.EndAt,
+		&instance.CheckInAt,
+		&instance.CheckOutAt,
+		&instance.StatusOverride,
+		&instance.Note,
+		&instance.CorrectedBy,
+		&instance.CreatedAt,
+		&instance.UpdatedAt,
+		&instance.Version,
+	}
+}
+
+// SyncShiftInstances 将排班计划今天之后的具体值班同步为 instances
+// 新增的会被插入，不再需要的则会被删除，已存在的会更新时间和负责人标记；
+// 今天以及之前的值班已经是考勤和工资的依据，不会被插入、修改或者删除，避免换班、请假等操作追溯改变考勤记录
+// 已经有考勤记录（签到过或者被黑心修正过）的值班也不会被修改或者删除
+func (r *Repository) SyncShiftInstances(schedulePlanID int64, instances []*domain.ShiftInstance) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := r.dbpool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	syncedIDs := make([]int64, 0, len(instances))
+	for _, instance := range instances {
+		query := `
+			INSERT INTO shift_instances (
+				schedule_plan_id,
+				schedule_template_shift_id,
+				date,
+				user_id,
+				is_principal,
+				start_at,
+				end_at
+			)
+			SELECT $1::BIGINT, $2::BIGINT, $3::DATE, $4::BIGINT, $5::BOOLEAN, $6::TIMESTAMPTZ, $7::TIMESTAMPTZ
+			WHERE $3::DATE > CURRENT_DATE
+			ON CONFLICT (schedule_plan_id, schedule_template_shift_id, date, user_id) DO UPDATE
+			SET
+				is_principal = EXCLUDED.is_principal,
+				start_at = EXCLUDED.start_at,
+				end_at = EXCLUDED.end_at
+			WHERE shift_instances.date > CURRENT_DATE
+				AND shift_instances.check_in_at IS NULL
+				AND shift_instances.check_out_at IS NULL
+				AND shift_instances.status_override IS NULL
+			RETURNING id
+		`
+		params := []any{
+			schedulePlanID,
+			instance.ShiftID,
+			instance.Date,
+			instance.UserID,
+			instance.IsPrincipal,
+			instance.StartAt,
+			instance.EndAt,
+		}
+		if err := tx.QueryRowContext(ctx, query, params...).Scan(&instance.ID); err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+
+			// 今天以及之前的值班不会被插入，已有考勤记录的值班不会被更新，它们都不会被 RETURNING 返回；
+			// 已有的值班需要单独查询它的 ID，之前没有生成过的则直接跳过
+			query := `
+				SELECT id FROM shift_instances
+				WHERE schedule_plan_id = $1 AND schedule_template_shift_id = $2 AND date = $3 AND user_id = $4
+			`
+			if err := tx.QueryRowContext(ctx, query, params[:4]...).Scan(&instance.ID); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					instance.ID = 0
+					continue
+				}
+				return err
+			}
+		}
+		syncedIDs = append(syncedIDs, instance.ID)
+	}
+
+	query := `
+		DELETE FROM shift_instances
+		WHERE schedule_plan_id = $1
+			AND NOT (id = ANY($2))
+			AND date > CURRENT_DATE
+			AND check_in_at IS NULL
+			AND check_out_at IS NULL
+			AND status_override IS NULL
+	`
+	if _, err := tx.ExecContext(ctx, query, schedulePlanID, syncedIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) GetShiftInstanceByID(id int64) (*domain.ShiftInstance, error) {
+	query := `SELECT ` + shiftInstanceColumns + ` FROM shift_instances WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	instance := &domain.ShiftInstance{}
+	if err := r.dbpool.QueryRowContext(ctx, query, id).Scan(shiftInstanceDst(instance)...); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// GetShiftInstancesBySchedulePlanID 获取排班计划的具体值班，date 和 userID 为空时表示不过滤
+func (r *Repository) GetShiftInstancesBySchedulePlanID(schedulePlanID int64, date *time.Time, userID *int64) ([]*domain.ShiftInstance, error) {
+	query := `
+		SELECT ` + shiftInstanceColumns + `
+		FROM shift_instances
+		WHERE schedule_plan_id = $1
+			AND ($2::DATE IS NULL OR date = $2::DATE)
+			AND ($3::BIGINT IS NULL OR user_id = $3::BIGINT)
+		ORDER BY start_at, is_principal DESC, user_id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := r.dbpool.QueryContext(ctx, query, schedulePlanID, date, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	instances := make([]*domain.ShiftInstance, 0)
+	for rows.Next() {
+		instance := &domain.ShiftInstance{}
+		if err := rows.Scan(shiftInstanceDst(instance)...); err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
+// GetShiftInstancesByDateRange 获取 [from, to] 之间所有排班计划的具体值班，userID 为空时表示不过滤
+func (r *Repository) GetShiftInstancesByDateRange(from time.Time, to time.Time, userID *int64) ([]*domain.ShiftInstance, error) {
+	query := `
+		SELECT ` + shiftInstanceColumns + `
+		FROM shift_instances
+		WHERE date BETWEEN $1 AND $2
+			AND ($3::BIGINT IS NULL OR user_id = $3::BIGINT)
+		ORDER BY start_at, is_principal DESC, user_id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := r.dbpool.QueryContext(ctx, query, from, to, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	instances := make([]*domain.ShiftInstance, 0)
+	for rows.Next() {
+		instance := &domain.ShiftInstance{}
+		if err := rows.Scan(shiftInstanceDst(instance)...); err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
+// UpdateShiftInstanceAttendance 更新值班的考勤信息
+func (r *Repository) UpdateShiftInstanceAttendance(instance *domain.ShiftInstance) error {
+	query := `
+		UPDATE shift_instances
+		SET
+			check_in_at = $1,
+			check_out_at = $2,
+			status_override = $3,
+			note = $4,
+			corrected_by = $5,
+			updated_at = NOW(),
+			version = version + 1
+		WHERE id = $6 AND version = $7
+		RETURNING updated_at, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	params := []any{
+		instance.CheckInAt,
+		instance.CheckOutAt,
+		instance.StatusOverride,
+		instance.Note,
+		instance.CorrectedBy,
+		instance.ID,
+		instance.Version,
+	}
+	if err := r.dbpool.QueryRowContext(ctx, query, params...).Scan(&instance.UpdatedAt, &instance.Version); err != nil {
+		return err
+	}
+
+	return nil
+}
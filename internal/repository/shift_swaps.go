@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+const shiftSwapColumns = `
+	id,
+	schedule_plan_id,
+	type,
+	proposer_id,
+	counterparty_id,
+	schedule_template_shift_id,
+	day_of_week,
+	counterparty_schedule_template_shift_id,
+	counterparty_day_of_week,
+	reason,
+	status,
+	reviewer_id,
+	created_at,
+	updated_at,
+	version
+`
+
+func shiftSwapDst(swap *domain.ShiftSwap) []any {
+	return []any{
+		&swap.ID,
+		&swap.SchedulePlanID,
+		&swap.Type,
+		&swap.ProposerID,
+		&swap.CounterpartyID,
+		&swap.ShiftID,
+		&swap.Day,
+		&swap.CounterpartyShiftID,
+		&swap.CounterpartyDay,
+		&swap.Reason,
+		&swap.Status,
+		&swap.ReviewerID,
+		&swap.CreatedAt,
+		&swap.UpdatedAt,
+		&swap.Version,
+	}
+}
+
+func (r *Repository) CreateShiftSwap(swap *domain.ShiftSwap) error {
+	query := `
+		INSERT INTO shift_swap_requests (
+			schedule_plan_id,
+			type,
+			proposer_id,
+			counterparty_id,
+			schedule_template_shift_id,
+			day_of_week,
+			counterparty_schedule_template_shift_id,
+			counterparty_day_of_week,
+			reason
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, status, created_at, updated_at, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	params := []any{
+		swap.SchedulePlanID,
+		swap.Type,
+		swap.ProposerID,
+		swap.CounterpartyID,
+		swap.ShiftID,
+		swap.Day,
+		swap.CounterpartyShiftID,
+		swap.CounterpartyDay,
+		swap.Reason,
+	}
+	dst := []any{&swap.ID, &swap.Status, &swap.CreatedAt, &swap.UpdatedAt, &swap.Version}
+	if err := r.dbpool.QueryRowContext(ctx, query, params...).Scan(dst...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) GetShiftSwapByID(id int64) (*domain.ShiftSwap, error) {
+	query := `SELECT ` + shiftSwapColumns + ` FROM shift_swap_requests WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	swap := &domain.ShiftSwap{}
+	if err := r.dbpool.QueryRowContext(ctx, query, id).Scan(shiftSwapDst(swap)...); err != nil {
+		return nil, err
+	}
+
+	return swap, nil
+}
+
+func (r *Repository) GetShiftSwapsBySchedulePlanID(schedulePlanID int64) ([]*domain.ShiftSwap, error) {
+	query := `SELECT ` + shiftSwapColumns + ` FROM shift_swap_requests WHERE schedule_plan_id = $1 ORDER BY created_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := r.dbpool.QueryContext(ctx, query, schedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	swaps := make([]*domain.ShiftSwap, 0)
+	for rows.Next() {
+		swap := &domain.ShiftSwap{}
+		if err := rows.Scan(shiftSwapDst(swap)...); err != nil {
+			return nil, err
+		}
+		swaps = append(swaps, swap)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return swaps, nil
+}
+
+// UpdateShiftSwapStatus 只更新换班申请的状态，用于接受（需要审批时）、拒绝、驳回、取消等不涉及排班结果的操作
+func (r *Repository) UpdateShiftSwapStatus(swap *domain.ShiftSwap) error {
+	query := `
+		UPDATE shift_swap_requests
+		SET
+			status = $1,
+			reviewer_id = $2,
+			updated_at = NOW(),
+			version = version + 1
+		WHERE id = $3 AND version = $4
+		RETURNING updated_at, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	params := []any{swap.Status, swap.ReviewerID, swap.ID, swap.Version}
+	if err := r.dbpool.QueryRowContext(ctx, query, params...).Scan(&swap.UpdatedAt, &swap.Version); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CompleteShiftSwap 在同一个事务中更新换班申请的状态以及排班结果中受影响的 (shift, day)
+// 排班结果通过 version 做乐观锁，如果在此期间排班结果被修改过则返回 sql.ErrNoRows
+func (r *Repository) CompleteShiftSwap(swap *domain.ShiftSwap, result *domain.SchedulingResult) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := r.dbpool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := `
+		UPDATE scheduling_results
+		SET version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING version
+	`
+	if err := tx.QueryRowContext(ctx, query, result.ID, result.Version).Scan(&result.Version); err != nil {
+		return err
+	}
+
+	// 只重写换班涉及到的 (shift, day)
+	type slot struct {
+		shiftID int64
+		day     int32
+	}
+	slots := []slot{{swap.ShiftID, swap.Day}}
+	if swap.Type == domain.ShiftSwapTypeExchange {
+		slots = append(slots, slot{*swap.CounterpartyShiftID, *swap.CounterpartyDay})
+	}
+
+	for _, s := range slots {
+		var item *domain.SchedulingResultShiftItem = nil
+		for i := range result.Shifts {
+			if result.Shifts[i].ShiftID != s.shiftID {
+				continue
+			}
+			for j := range result.Shifts[i].Items {
+				if result.Shifts[i].Items[j].Day == s.day {
+					item = &result.Shifts[i].Items[j]
+				}
+			}
+		}
+		if item == nil {
+			return sql.ErrNoRows
+		}
+
+		query := `
+			UPDATE scheduling_result_shift_items srsi
+			SET principal_id = $1
+			FROM scheduling_result_shifts srs
+			WHERE srsi.scheduling_result_shift_id = srs.id
+				AND srs.scheduling_result_id = $2
+				AND srs.schedule_template_shift_id = $3
+				AND srsi.day_of_week = $4
+			RETURNING srsi.id
+		`
+		var itemID int64
+		if err := tx.QueryRowContext(ctx, query, item.PrincipalID, result.ID, s.shiftID, s.day).Scan(&itemID); err != nil {
+			return err
+		}
+
+		query = `DELETE FROM scheduling_result_shift_item_assistants WHERE scheduling_result_shift_item_id = $1`
+		if _, err := tx.ExecContext(ctx, query, itemID); err != nil {
+			return err
+		}
+
+		for _, assistantID := range item.AssistantIDs {
+			query := `
+				INSERT INTO scheduling_result_shift_item_assistants (scheduling_result_shift_item_id, assistant_id)
+				VALUES ($1, $2)
+			`
+			if _, err := tx.ExecContext(ctx, query, itemID, assistantID); err != nil {
+				return err
+			}
+		}
+	}
+
+	query = `
+		UPDATE shift_swap_requests
+		SET
+			status = $1,
+			reviewer_id = $2,
+			updated_at = NOW(),
+			version = version + 1
+		WHERE id = $3 AND version = $4
+		RETURNING updated_at, version
+	`
+	params := []any{swap.Status, swap.ReviewerID, swap.ID, swap.Version}
+	if err := tx.QueryRowContext(ctx, query, params...).Scan(&swap.UpdatedAt, &swap.Version); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
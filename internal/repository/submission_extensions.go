@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+const submissionExtensionColumns = `
+	id,
+	schedule_plan_id,
+	user_id,
+	deadline,
+	reason,
+	granted_by,
+	created_at,
+	version
+`
+
+func submissionExtensionDst(extension *domain.SubmissionExtension) []any {
+	return []any{
+		&extension.ID,
+		&extension.SchedulePlanID,
+		&extension.UserID,
+		&extension.Deadline,
+		&extension.Reason,
+		&extension.GrantedBy,
+		&extension.CreatedAt,
+		&extension.Version,
+	}
+}
+
+// UpsertSubmissionExtension 为用户设置单独的截止时间，已经设置过的会被覆盖
+func (r *Repository) UpsertSubmissionExtension(extension *domain.SubmissionExtension) error {
+	query := `
+		INSERT INTO submission_extensions (schedule_plan_id, user_id, deadline, reason, granted_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (schedule_plan_id, user_id) DO UPDATE
+		SET
+			deadline = EXCLUDED.deadline,
+			reason = EXCLUDED.reason,
+			granted_by = EXCLUDED.granted_by,
+			version = submission_extensions.version + 1
+		RETURNING ` + submissionExtensionColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	params := []any{extension.SchedulePlanID, extension.UserID, extension.Deadline, extension.Reason, extension.GrantedBy}
+	if err := r.dbpool.QueryRowContext(ctx, query, params...).Scan(submissionExtensionDst(extension)...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) GetSubmissionExtension(schedulePlanID int64, userID int64) (*domain.SubmissionExtension, error) {
+	query := `SELECT ` + submissionExtensionColumns + ` FROM submission_extensions WHERE schedule_plan_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	extension := &domain.SubmissionExtension{}
+	if err := r.dbpool.QueryRowContext(ctx, query, schedulePlanID, userID).Scan(submissionExtensionDst(extension)...); err != nil {
+		return nil, err
+	}
+
+	return extension, nil
+}
+
+func (r *Repository) GetSubmissionExtensionsBySchedulePlanID(schedulePlanID int64) ([]*domain.SubmissionExtension, error) {
+	query := `SELECT ` + submissionExtensionColumns + ` FROM submission_extensions WHERE schedule_plan_id = $1 ORDER BY deadline, user_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := r.dbpool.QueryContext(ctx, query, schedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	extensions := make([]*domain.SubmissionExtension, 0)
+	for rows.Next() {
+		extension := &domain.SubmissionExtension{}
+		if err := rows.Scan(submissionExtensionDst(extension)...); err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, extension)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return extensions, nil
+}
+
+func (r *Repository) DeleteSubmissionExtension(schedulePlanID int64, userID int64) error {
+	query := `DELETE FROM submission_extensions WHERE schedule_plan_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	if _, err := r.dbpool.ExecContext(ctx, query, schedulePlanID, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// GetUsersWithoutSubmission 获取所有可以参与排班计划但还没有提交空闲时间的在职用户
+// 只有还没有审批或者被驳回的逾期提交的用户和排班时一样视为没有提交
+func (r *Repository) GetUsersWithoutSubmission(schedulePlanID int64) ([]*domain.User, error) {
+	query := `
+		SELECT u.id, u.username, u.full_name, u.email, u.role, u.is_active, u.created_at, u.version
+		FROM users u
+		INNER JOIN schedule_plan_effective_participants p ON p.user_id = u.id AND p.schedule_plan_id = $1
+		WHERE NOT EXISTS (
+				SELECT 1 FROM availability_submissions s
+				WHERE s.user_id = u.id AND s.schedule_plan_id = $1 AND (s.late_status IS NULL OR s.late_status = '已批准')
+			)
+		ORDER BY u.id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := r.dbpool.QueryContext(ctx, query, schedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*domain.User, 0)
+	for rows.Next() {
+		user := &domain.User{}
+		dst := []any{&user.ID, &user.Username, &user.FullName, &user.Email, &user.Role, &user.IsActive, &user.CreatedAt, &user.Version}
+		if err := rows.Scan(dst...); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// ClaimSubmissionReminders 为用户登记提醒记录，返回本次真正登记成功（即之前没有提醒过）的用户 ID
+// 同一个 (plan, user, hoursBefore) 只会登记一次，以此保证同一条提醒不会重复发送
+func (r *Repository) ClaimSubmissionReminders(schedulePlanID int64, hoursBefore int, userIDs []int64) ([]int64, error) {
+	query := `
+		INSERT INTO submission_reminders (schedule_plan_id, user_id, hours_before)
+		SELECT $1, user_id, $2 FROM UNNEST($3::BIGINT[]) AS user_id
+		ON CONFLICT (schedule_plan_id, user_id, hours_before) DO NOTHING
+		RETURNING user_id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := r.dbpool.QueryContext(ctx, query, schedulePlanID, hoursBefore, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	claimed := make([]int64, 0)
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+// ReleaseSubmissionReminders 删除提醒记录，用于邮件投递失败后让下一次检查重新发送
+func (r *Repository) ReleaseSubmissionReminders(schedulePlanID int64, hoursBefore int, userIDs []int64) error {
+	query := `
+		DELETE FROM submission_reminders
+		WHERE schedule_plan_id = $1 AND hours_before = $2 AND user_id = ANY($3)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	if _, err := r.dbpool.ExecContext(ctx, query, schedulePlanID, hoursBefore, userIDs); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// GetSubmissionRules 获取排班计划的提交要求，没有设置任何要求时返回空的要求
+func (r *Repository) GetSubmissionRules(schedulePlanID int64) (*domain.SubmissionRules, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rules := &domain.SubmissionRules{
+		SchedulePlanID:   schedulePlanID,
+		RoleRules:        make([]domain.SubmissionRoleRule, 0),
+		RequiredShiftIDs: make([]int64, 0),
+	}
+
+	query := `
+		SELECT role, min_slots, min_principal_slots, min_distinct_days
+		FROM submission_role_rules
+		WHERE schedule_plan_id = $1
+		ORDER BY role
+	`
+	rows, err := r.dbpool.QueryContext(ctx, query, schedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rule domain.SubmissionRoleRule
+		if err := rows.Scan(&rule.Role, &rule.MinSlots, &rule.MinPrincipalSlots, &rule.MinDistinctDays); err != nil {
+			return nil, err
+		}
+		rules.RoleRules = append(rules.RoleRules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT shift_id FROM submission_required_shifts WHERE schedule_plan_id = $1 ORDER BY shift_id`
+	rows, err = r.dbpool.QueryContext(ctx, query, schedulePlanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shiftID int64
+		if err := rows.Scan(&shiftID); err != nil {
+			return nil, err
+		}
+		rules.RequiredShiftIDs = append(rules.RequiredShiftIDs, shiftID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// SetSubmissionRules 用 rules 替换排班计划的提交要求
+func (r *Repository) SetSubmissionRules(rules *domain.SubmissionRules) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := r.dbpool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM submission_role_rules WHERE schedule_plan_id = $1`, rules.SchedulePlanID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM submission_required_shifts WHERE schedule_plan_id = $1`, rules.SchedulePlanID); err != nil {
+		return err
+	}
+
+	for _, rule := range rules.RoleRules {
+		query := `
+			INSERT INTO submission_role_rules (schedule_plan_id, role, min_slots, min_principal_slots, min_distinct_days)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+		params := []any{rules.SchedulePlanID, rule.Role, rule.MinSlots, rule.MinPrincipalSlots, rule.MinDistinctDays}
+		if _, err := tx.ExecContext(ctx, query, params...); err != nil {
+			return err
+		}
+	}
+
+	if len(rules.RequiredShiftIDs) > 0 {
+		query := `
+			INSERT INTO submission_required_shifts (schedule_plan_id, shift_id)
+			SELECT $1, shift_id FROM UNNEST($2::BIGINT[]) AS shift_id
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.ExecContext(ctx, query, rules.SchedulePlanID, rules.RequiredShiftIDs); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// copySubmissionRules 在事务中将排班计划 fromID 的提交要求复制到排班计划 toID，返回无法对应到新模板的必须提交的班次数
+// 两个排班计划使用的模板快照不一定相同，必须提交的班次按照开始时间、结束时间、值班点和适用的天数对应到新模板的班次上
+func copySubmissionRules(ctx context.Context, tx *sql.Tx, fromID int64, toID int64) (int, error) {
+	query := `
+		INSERT INTO submission_role_rules (schedule_plan_id, role, min_slots, min_principal_slots, min_distinct_days)
+		SELECT $2, role, min_slots, min_principal_slots, min_distinct_days
+		FROM submission_role_rules
+		WHERE schedule_plan_id = $1
+	`
+	if _, err := tx.ExecContext(ctx, query, fromID, toID); err != nil {
+		return 0, err
+	}
+
+	query = `
+		WITH days AS (
+			SELECT shift_id, ARRAY_AGG(day ORDER BY day) AS days
+			FROM schedule_template_shift_applicable_days
+			GROUP BY shift_id
+		), old AS (
+			SELECT sts.id, sts.start_time, sts.end_time, sts.location, d.days
+			FROM submission_required_shifts rs
+			INNER JOIN schedule_template_shifts sts ON sts.id = rs.shift_id
+			LEFT JOIN days d ON d.shift_id = sts.id
+			WHERE rs.schedule_plan_id = $1
+		), new AS (
+			SELECT sts.id, sts.start_time, sts.end_time, sts.location, d.days
+			FROM schedule_template_shifts sts
+			INNER JOIN schedule_plans sp ON sp.id = $2 AND sp.schedule_template_id = sts.template_id
+			LEFT JOIN days d ON d.shift_id = sts.id
+		), mapped AS (
+			SELECT old.id AS old_id, new.id AS new_id
+			FROM old
+			INNER JOIN new ON new.start_time = old.start_time
+				AND new.end_time = old.end_time
+				AND new.location = old.location
+				AND new.days IS NOT DISTINCT FROM old.days
+		), inserted AS (
+			INSERT INTO submission_required_shifts (schedule_plan_id, shift_id)
+			SELECT DISTINCT $2::BIGINT, new_id FROM mapped
+			ON CONFLICT DO NOTHING
+		)
+		SELECT COUNT(*) FROM old WHERE id NOT IN (SELECT old_id FROM mapped)
+	`
+	var unmapped int
+	if err := tx.QueryRowContext(ctx, query, fromID, toID).Scan(&unmapped); err != nil {
+		return 0, err
+	}
+
+	return unmapped, nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// GetSubmissionStats 统计排班计划的提交情况，所有统计都在数据库中完成
+// minPrincipals 表示每个需要负责人的 (shift, day) 至少需要多少个有空的、能够担任负责人的用户
+func (r *Repository) GetSubmissionStats(plan *domain.SchedulePlan, minPrincipals int) (*domain.SubmissionStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
+	defer cancel()
+
+	stats := &domain.SubmissionStats{
+		SchedulePlanID:     plan.ID,
+		Submitted:          make([]*domain.SubmissionStatsUser, 0),
+		NotSubmitted:       make([]*domain.SubmissionStatsUser, 0),
+		Slots:              make([]*domain.SubmissionStatsSlot, 0),
+		PrincipalShortages: make([]*domain.SubmissionStatsSlot, 0),
+		Roles:              make([]*domain.SubmissionStatsRole, 0),
+	}
+
+	// 参与名单中谁提交了、谁还没有提交，已经离职或者不在名单中但提交过的用户也算在已提交里
+	// 和排班一样只统计当前生效的提交，还没有被批准的逾期提交不算
+	query := `
+		SELECT u.id, u.username, u.full_name, u.role, s.created_at
+		FROM users u
+		LEFT JOIN (` + effectiveSubmissionsQuery + `) s ON s.user_id = u.id
+		LEFT JOIN schedule_plan_effective_participants p ON p.user_id = u.id AND p.schedule_plan_id = $1
+		WHERE p.user_id IS NOT NULL OR s.id IS NOT NULL
+		ORDER BY s.created_at NULLS LAST, u.id
+	`
+	rows, err := r.dbpool.QueryContext(ctx, query, plan.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := &domain.SubmissionStatsUser{}
+		if err := rows.Scan(&user.UserID, &user.Username, &user.FullName, &user.Role, &user.SubmittedAt); err != nil {
+			return nil, err
+		}
+		if user.SubmittedAt != nil {
+			stats.Submitted = append(stats.Submitted, user)
+		} else {
+			stats.NotSubmitted = append(stats.NotSubmitted, user)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 每个 (shift, day) 有空的人数，只统计参与名单中的在职用户，需要的人数按照班次在这一天的要求计算
+	query = `
+		WITH available AS (
+			SELECT asi.schedule_template_shift_id AS shift_id, asd.day_of_week AS day, u.id AS user_id, u.role
+			FROM (` + effectiveSubmissionsQuery + `) s
+			INNER JOIN users u ON u.id = s.user_id
+			INNER JOIN schedule_plan_effective_participants p ON p.user_id = u.id AND p.schedule_plan_id = s.schedule_plan_id
+			INNER JOIN availability_submission_items asi ON asi.availability_submission_id = s.id
+			INNER JOIN availability_submission_item_available_days asd ON asd.availability_submission_item_id = asi.id
+		)
+		SELECT
+			sts.id,
+			sts.start_time,
+			sts.end_time,
+			sts.location,
+			stsad.day,
+			COALESCE(dr.required_assistant_number, sts.required_assistant_number) AS required_assistant_number,
+			COALESCE(dr.principal_required, TRUE) AS principal_required,
+			COUNT(DISTINCT a.user_id) AS available_count,
+			COUNT(DISTINCT a.user_id) FILTER (WHERE a.role IN ('资深助理', '黑心')) AS available_principal_count
+		FROM schedule_template_shifts sts
+		INNER JOIN schedule_template_shift_applicable_days stsad ON stsad.shift_id = sts.id
+		LEFT JOIN schedule_template_shift_day_requirements dr ON dr.shift_id = sts.id AND dr.day = stsad.day
+		LEFT JOIN available a ON a.shift_id = sts.id AND a.day = stsad.day
+		WHERE sts.template_id = $2
+		GROUP BY sts.id, sts.start_time, sts.end_time, sts.location, stsad.day, sts.required_assistant_number, dr.required_assistant_number, dr.principal_required
+		ORDER BY sts.location, sts.start_time, sts.id, stsad.day
+	`
+	rows, err = r.dbpool.QueryContext(ctx, query, plan.ID, plan.ScheduleTemplateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		slot := &domain.SubmissionStatsSlot{}
+		dst := []any{
+			&slot.ShiftID,
+			&slot.StartTime,
+			&slot.EndTime,
+			&slot.Location,
+			&slot.Day,
+			&slot.RequiredAssistantNumber,
+			&slot.PrincipalRequired,
+			&slot.AvailableCount,
+			&slot.AvailablePrincipalCount,
+		}
+		if err := rows.Scan(dst...); err != nil {
+			return nil, err
+		}
+		slot.Understaffed = slot.AvailableCount < slot.RequiredAssistantNumber
+		// 不需要负责人的 (shift, day) 不统计负责人短缺
+		slot.PrincipalShortage = slot.PrincipalRequired && int(slot.AvailablePrincipalCount) < minPrincipals
+		stats.Slots = append(stats.Slots, slot)
+		if slot.PrincipalShortage {
+			stats.PrincipalShortages = append(stats.PrincipalShortages, slot)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 按照身份统计参与名单中的提交率，没有用户的身份也会返回
+	query = `
+		SELECT
+			r.role,
+			COUNT(u.id) AS total,
+			COUNT(s.id) AS submitted,
+			COALESCE(ROUND(COUNT(s.id)::NUMERIC / NULLIF(COUNT(u.id), 0), 4), 0)::FLOAT8 AS rate
+		FROM UNNEST(ENUM_RANGE(NULL::user_role)) AS r(role)
+		LEFT JOIN users u ON u.role = r.role AND u.id IN (
+			SELECT user_id FROM schedule_plan_effective_participants WHERE schedule_plan_id = $1
+		)
+		LEFT JOIN (` + effectiveSubmissionsQuery + `) s ON s.user_id = u.id
+		GROUP BY r.role
+		ORDER BY r.role
+	`
+	rows, err = r.dbpool.QueryContext(ctx, query, plan.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		role := &domain.SubmissionStatsRole{}
+		if err := rows.Scan(&role.Role, &role.Total, &role.Submitted, &role.Rate); err != nil {
+			return nil, err
+		}
+		stats.Roles = append(stats.Roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -154,3 +154,36 @@ func (r *Repository) CheckEmailIfExists(email string) (bool, error) {
 
 	return isExists, nil
 }
+
+// CreateUsers 在同一个事务中批量创建用户，任意一个失败则全部回滚
+func (r *Repository) CreateUsers(users []*domain.User) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Database.TransactionTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := r.dbpool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := `
+		INSERT INTO users (username, password_hash, full_name, email, role)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, is_active, created_at, version
+	`
+
+	for _, user := range users {
+		args := []any{user.Username, user.PasswordHash, user.FullName, user.Email, user.Role}
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.IsActive, &user.CreatedAt, &user.Version); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
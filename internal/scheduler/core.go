@@ -4,7 +4,8 @@ import (
 	"math"
 	"math/rand"
 	"slices"
-	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
 )
 
 // randomInitChromosome 随机初始化一个染色体
@@ -15,16 +16,23 @@ func (s *Scheduler) randomInitChromosome() *Chromosome {
 		for _, day := range shift.ApplicableDays {
 			var principalID *int64 = nil
 
+			// 这一天需要的人数（包括负责人）以及是否需要负责人
+			requiredNum, principalRequired := shift.RequirementOn(day)
+
 			// 选出可以担当此 (shift, day) 的负责人候选
 			var principalCandidatesIDs []int64 = []int64{}
 			for _, user := range s.users {
 				if isSeniorOrBlackCore(user) && slices.Contains(s.availableMap[shift.ID][day], user.ID) {
+					if s.isBusy(genes, shift.ID, day, user.ID) {
+						// 已经被安排到了时间重叠的班次中
+						continue
+					}
 					principalCandidatesIDs = append(principalCandidatesIDs, user.ID)
 				}
 			}
 
 			// 随机选出一个负责人
-			if len(principalCandidatesIDs) > 0 {
+			if principalRequired && len(principalCandidatesIDs) > 0 {
 				principalID = &principalCandidatesIDs[rand.Intn(len(principalCandidatesIDs))]
 			}
 
@@ -36,31 +44,39 @@ func (s *Scheduler) randomInitChromosome() *Chromosome {
 						// 确保已经被选为负责人的助理，不会在这一轮中被选中
 						continue
 					}
+					if s.isBusy(genes, shift.ID, day, user.ID) {
+						// 已经被安排到了时间重叠的班次中
+						continue
+					}
 					assistantCandidatesIDs = append(assistantCandidatesIDs, user.ID)
 				}
 			}
 
 			// 随机选择助理
-			chosenNum := min(int(shift.RequiredAssistantNumber-1), len(assistantCandidatesIDs))
+			// 需要负责人时负责人占用一个名额
+			assistantNum := requiredNum
+			if principalRequired {
+				assistantNum--
+			}
+			chosenNum := min(int(assistantNum), len(assistantCandidatesIDs))
 			// 打乱助理候选顺序
 			rand.Shuffle(len(assistantCandidatesIDs), func(i, j int) {
 				assistantCandidatesIDs[i], assistantCandidatesIDs[j] = assistantCandidatesIDs[j], assistantCandidatesIDs[i]
 			})
 			chosenAssistantIDs := assistantCandidatesIDs[:chosenNum]
 
-			// 计算工作时长
-			startTime, _ := time.Parse("15:04:05", shift.StartTime)
-			endTime, _ := time.Parse("15:04:05", shift.EndTime)
-			workDuration := endTime.Sub(startTime).Hours()
+			// 计算工作时长，跨越午夜的班次也能正确计算
+			workDuration := utils.ShiftDuration(shift).Hours()
 
 			// 生成基因
 			genes = append(genes, &Gene{
-				shiftID:      shift.ID,
-				day:          day,
-				principalID:  principalID,
-				assistantIDs: chosenAssistantIDs,
-				requiredNum:  shift.RequiredAssistantNumber,
-				workDuration: workDuration,
+				shiftID:           shift.ID,
+				day:               day,
+				principalID:       principalID,
+				assistantIDs:      chosenAssistantIDs,
+				requiredNum:       requiredNum,
+				workDuration:      workDuration,
+				principalRequired: principalRequired,
 			})
 		}
 	}
@@ -188,12 +204,16 @@ func (s *Scheduler) mutate(ch *Chromosome) {
 					// 如果这个用户已经被选到这个班次中当助理了，那么就不要把它放入候选了
 					continue
 				}
+				if s.isBusy(ch.genes, ch.genes[i].shiftID, ch.genes[i].day, user.ID) {
+					// 如果这个用户已经被安排到了时间重叠的班次中，那么就不要把它放入候选了
+					continue
+				}
 
 				principalCandidatesIDs = append(principalCandidatesIDs, user.ID)
 			}
 		}
 
-		if len(principalCandidatesIDs) > 0 {
+		if ch.genes[i].principalRequired && len(principalCandidatesIDs) > 0 {
 			ch.genes[i].principalID = &principalCandidatesIDs[rand.Intn(len(principalCandidatesIDs))]
 		}
 
@@ -216,6 +236,10 @@ func (s *Scheduler) mutate(ch *Chromosome) {
 						// 如果这个用户已经被选到这个班次中当助理了，那么就不要把它放入候选了
 						continue
 					}
+					if s.isBusy(ch.genes, ch.genes[i].shiftID, ch.genes[i].day, user.ID) {
+						// 如果这个用户已经被安排到了时间重叠的班次中，那么就不要把它放入候选了
+						continue
+					}
 
 					assistantCandidatesIDs = append(assistantCandidatesIDs, user.ID)
 				}
@@ -227,3 +251,31 @@ func (s *Scheduler) mutate(ch *Chromosome) {
 		}
 	}
 }
+
+// isBusy 判断用户是否已经被 genes 安排到了与 (shiftID, day) 时间重叠的班次中
+func (s *Scheduler) isBusy(genes []*Gene, shiftID int64, day int32, userID int64) bool {
+	for _, gene := range genes {
+		if !slices.Contains(s.overlaps[slot{shiftID, day}], slot{gene.shiftID, gene.day}) {
+			continue
+		}
+		if (gene.principalID != nil && *gene.principalID == userID) || slices.Contains(gene.assistantIDs, userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// 修复
+// 交叉会把来自不同染色体的基因拼在一起，可能导致同一个人被安排到时间重叠的班次中，这里保留先出现的安排
+func (s *Scheduler) repair(ch *Chromosome) {
+	for i, gene := range ch.genes {
+		earlier := ch.genes[:i]
+
+		if gene.principalID != nil && s.isBusy(earlier, gene.shiftID, gene.day, *gene.principalID) {
+			gene.principalID = nil
+		}
+		gene.assistantIDs = slices.DeleteFunc(gene.assistantIDs, func(userID int64) bool {
+			return s.isBusy(earlier, gene.shiftID, gene.day, userID)
+		})
+	}
+}
@@ -2,12 +2,19 @@ package scheduler
 
 // Gene: 表示对某个 (shift, day) 的排班决策
 type Gene struct {
-	shiftID      int64
-	day          int32
-	principalID  *int64  // 如果 PrincipalID 为 nil，则表示这个 (shift, day) 没有负责人
-	assistantIDs []int64 // 如果 AssistantIDs 为空，则表示这个 (shift, day) 没有助理
-	requiredNum  int32
-	workDuration float64
+	shiftID           int64
+	day               int32
+	principalID       *int64  // 如果 PrincipalID 为 nil，则表示这个 (shift, day) 没有负责人
+	assistantIDs      []int64 // 如果 AssistantIDs 为空，则表示这个 (shift, day) 没有助理
+	requiredNum       int32
+	workDuration      float64
+	principalRequired bool // 如果 principalRequired 为 false，则表示这个 (shift, day) 不安排负责人，requiredNum 个人都是助理
+}
+
+// slot: 表示某个 (shift, day)
+type slot struct {
+	shiftID int64
+	day     int32
 }
 
 // Chromosome: 整个排班表
@@ -16,6 +16,8 @@ type Scheduler struct {
 	shifts       []*domain.ScheduleTemplateShift
 	submissions  []*domain.AvailabilitySubmission // 仅做最后的校验使用
 	availableMap map[int64]map[int32][]int64      // {shiftID: {day: [userID1, userID2, ...]}}
+	overlaps     map[slot][]slot                  // {(shift, day): [与之时间重叠的其他 (shift, day)]}，例如不同值班点同时进行的班次
+	template     *domain.ScheduleTemplate
 }
 
 func New(parameters *Parameters, users []*domain.User, template *domain.ScheduleTemplate, availableSubmissions []*domain.AvailabilitySubmission) (*Scheduler, error) {
@@ -25,12 +27,29 @@ func New(parameters *Parameters, users []*domain.User, template *domain.Schedule
 		shifts:       make([]*domain.ScheduleTemplateShift, 0),
 		submissions:  availableSubmissions,
 		availableMap: make(map[int64]map[int32][]int64),
+		overlaps:     make(map[slot][]slot),
+		template:     template,
 	}
 
 	for _, shift := range template.Shifts {
 		s.shifts = append(s.shifts, &shift)
 	}
 
+	for _, shift := range s.shifts {
+		for _, day := range shift.ApplicableDays {
+			for _, other := range s.shifts {
+				for _, otherDay := range other.ApplicableDays {
+					if other.ID == shift.ID && otherDay == day {
+						continue
+					}
+					if utils.ScheduleTemplateShiftsOverlapOn(shift, day, other, otherDay, template.RotationWeeks) {
+						s.overlaps[slot{shift.ID, day}] = append(s.overlaps[slot{shift.ID, day}], slot{other.ID, otherDay})
+					}
+				}
+			}
+		}
+	}
+
 	for _, submission := range availableSubmissions {
 		userID := submission.UserID
 
@@ -100,12 +119,13 @@ func (s *Scheduler) Schedule() ([]*domain.SchedulingResultShift, error) {
 			bestChromosomeEver.genes = make([]*Gene, len(pop[genBestIndex].genes))
 			for i := 0; i < len(pop[genBestIndex].genes); i++ {
 				bestChromosomeEver.genes[i] = &Gene{
-					shiftID:      pop[genBestIndex].genes[i].shiftID,
-					day:          pop[genBestIndex].genes[i].day,
-					principalID:  pop[genBestIndex].genes[i].principalID,
-					assistantIDs: make([]int64, len(pop[genBestIndex].genes[i].assistantIDs)),
-					requiredNum:  pop[genBestIndex].genes[i].requiredNum,
-					workDuration: pop[genBestIndex].genes[i].workDuration,
+					shiftID:           pop[genBestIndex].genes[i].shiftID,
+					day:               pop[genBestIndex].genes[i].day,
+					principalID:       pop[genBestIndex].genes[i].principalID,
+					assistantIDs:      make([]int64, len(pop[genBestIndex].genes[i].assistantIDs)),
+					requiredNum:       pop[genBestIndex].genes[i].requiredNum,
+					workDuration:      pop[genBestIndex].genes[i].workDuration,
+					principalRequired: pop[genBestIndex].genes[i].principalRequired,
 				}
 				copy(bestChromosomeEver.genes[i].assistantIDs, pop[genBestIndex].genes[i].assistantIDs)
 			}
@@ -133,6 +153,10 @@ func (s *Scheduler) Schedule() ([]*domain.SchedulingResultShift, error) {
 			s.mutate(p1)
 			s.mutate(p2)
 
+			// 交叉之后可能出现同一个人被安排到时间重叠的班次中
+			s.repair(p1)
+			s.repair(p2)
+
 			newPop = append(newPop, p1)
 
 			if len(newPop) < int(s.parameters.PopulationSize) {
@@ -181,6 +205,9 @@ func (s *Scheduler) Schedule() ([]*domain.SchedulingResultShift, error) {
 	if err := utils.ValidIfExistsDuplicateAssistant(schedulingResult); err != nil {
 		return nil, err
 	}
+	if err := utils.ValidateSchedulingResultOverlaps(schedulingResult, s.template); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
@@ -109,9 +109,10 @@ func SeedRealData(r *repository.Repository) {
 
 	// 插入排班计划
 	st := &domain.ScheduleTemplate{
-		Name:        "2025春新学期模板",
-		Description: "前台人数从 4 人增加到 5 人，小黑屋人数从 3 人增加到 4 人",
-		Shifts:      make([]domain.ScheduleTemplateShift, 0),
+		Name:          "2025春新学期模板",
+		Description:   "前台人数从 4 人增加到 5 人，小黑屋人数从 3 人增加到 4 人",
+		RotationWeeks: 1,
+		Shifts:        make([]domain.ScheduleTemplateShift, 0),
 	}
 
 	for _, value := range ShiftHeaderMap {
@@ -140,6 +141,13 @@ func SeedRealData(r *repository.Repository) {
 		return
 	}
 
+	// 提交记录需要引用排班计划所使用的模板快照中的班次
+	st, err = r.GetScheduleTemplate(sp.ScheduleTemplateID)
+	if err != nil {
+		slog.Error("获取排班模板快照失败", "error", err)
+		return
+	}
+
 	// 插入助理及其提交记录到数据库中
 	for _, record := range records {
 		// 先尝试获取助理
@@ -0,0 +1,18 @@
+package timetable
+
+import (
+	"html/template"
+	"io"
+)
+
+const htmlTemplatePath = "./templates/scheduling_result_print.html"
+
+// RenderHTML 将周排班表渲染为可打印的 HTML
+func (tt *Timetable) RenderHTML(w io.Writer) error {
+	tmpl, err := template.ParseFiles(htmlTemplatePath)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, tt)
+}
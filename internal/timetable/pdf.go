@@ -0,0 +1,145 @@
+package timetable
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+var ErrPDFFontNotConfigured = errors.New("未配置 PDF 字体，无法导出 PDF")
+
+const (
+	pdfFontFamily  = "timetable"
+	pdfMargin      = 10.0
+	pdfTimeColumn  = 24.0
+	pdfLineHeight  = 5.0
+	pdfCellPadding = 1.5
+)
+
+// RenderPDF 将周排班表渲染为 A4 横向的 PDF
+// 由于排班表中包含中文，必须指定一个支持中文的 TTF 字体文件
+func (tt *Timetable) RenderPDF(w io.Writer, fontPath string) error {
+	if fontPath == "" {
+		return ErrPDFFontNotConfigured
+	}
+
+	font, err := os.ReadFile(fontPath)
+	if err != nil {
+		return fmt.Errorf("无法读取 PDF 字体: %w", err)
+	}
+
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "", font)
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("无法加载 PDF 字体: %w", err)
+	}
+
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, pdfMargin)
+	pdf.AliasNbPages("")
+
+	pageWidth, pageHeight := pdf.GetPageSize()
+	dayColumn := (pageWidth - 2*pdfMargin - pdfTimeColumn) / 7
+
+	// 每一页的页眉都带上计划名称和启用时间，轮换模板还会带上当前是第几周
+	var weekName string
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont(pdfFontFamily, "", 16)
+		title := tt.PlanName
+		if weekName != "" {
+			title += "（" + weekName + "）"
+		}
+		pdf.CellFormat(0, 8, title, "", 1, "L", false, 0, "")
+		pdf.SetFont(pdfFontFamily, "", 10)
+		pdf.CellFormat(0, 6, fmt.Sprintf("启用时间：%s 至 %s", tt.ActiveStartDate, tt.ActiveEndDate), "B", 1, "L", false, 0, "")
+		pdf.Ln(3)
+		tt.drawPDFTableHead(pdf, dayColumn)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin)
+		pdf.SetFont(pdfFontFamily, "", 8)
+		pdf.CellFormat(0, 5, fmt.Sprintf("生成时间：%s    第 %d/{nb} 页", tt.GeneratedAt, pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	// 轮换模板的每一周从新的一页开始
+	for _, week := range tt.Weeks {
+		weekName = week.Name
+		pdf.AddPage()
+		pdf.SetFont(pdfFontFamily, "", 9)
+
+		for _, row := range week.Rows {
+			// 先计算这一行需要的高度，取所有格子中行数最多的那个
+			cellLines := [7][]string{}
+			timeLines := []string{row.StartTime, "- " + row.EndTime}
+			if row.Location != "" {
+				timeLines = append(pdf.SplitText(row.Location, pdfTimeColumn-2*pdfCellPadding), timeLines...)
+			}
+			maxLines := len(timeLines) // 时间列占两行，有值班点时再加上值班点
+			for i, cell := range row.Cells {
+				if !cell.Applicable {
+					continue
+				}
+				if cell.Principal != "" {
+					cellLines[i] = append(cellLines[i], pdf.SplitText(cell.Principal+"（负责人）", dayColumn-2*pdfCellPadding)...)
+				}
+				for _, assistant := range cell.Assistants {
+					cellLines[i] = append(cellLines[i], pdf.SplitText(assistant, dayColumn-2*pdfCellPadding)...)
+				}
+				maxLines = max(maxLines, len(cellLines[i]))
+			}
+			rowHeight := float64(maxLines)*pdfLineHeight + 2*pdfCellPadding
+
+			// 放不下则换页，表头会在页眉中重新绘制
+			_, y := pdf.GetXY()
+			if y+rowHeight > pageHeight-pdfMargin-pdfLineHeight {
+				pdf.AddPage()
+				pdf.SetFont(pdfFontFamily, "", 9)
+				_, y = pdf.GetXY()
+			}
+
+			x := pdfMargin
+
+			// 时间列
+			pdf.Rect(x, y, pdfTimeColumn, rowHeight, "D")
+			pdf.SetXY(x, y+(rowHeight-float64(len(timeLines))*pdfLineHeight)/2)
+			for _, line := range timeLines {
+				pdf.CellFormat(pdfTimeColumn, pdfLineHeight, line, "", 2, "C", false, 0, "")
+			}
+			x += pdfTimeColumn
+
+			// 每一天的格子
+			for i, cell := range row.Cells {
+				if cell.Applicable {
+					pdf.Rect(x, y, dayColumn, rowHeight, "D")
+				} else {
+					pdf.SetFillColor(233, 236, 239)
+					pdf.Rect(x, y, dayColumn, rowHeight, "FD")
+				}
+
+				for j, line := range cellLines[i] {
+					pdf.SetXY(x+pdfCellPadding, y+pdfCellPadding+float64(j)*pdfLineHeight)
+					pdf.CellFormat(dayColumn-2*pdfCellPadding, pdfLineHeight, line, "", 0, "L", false, 0, "")
+				}
+
+				x += dayColumn
+			}
+
+			pdf.SetXY(pdfMargin, y+rowHeight)
+		}
+	}
+
+	return pdf.Output(w)
+}
+
+func (tt *Timetable) drawPDFTableHead(pdf *gofpdf.Fpdf, dayColumn float64) {
+	pdf.SetFont(pdfFontFamily, "", 10)
+	pdf.SetFillColor(240, 242, 245)
+	pdf.CellFormat(pdfTimeColumn, 7, "班次", "1", 0, "C", true, 0, "")
+	for _, name := range tt.DayNames {
+		pdf.CellFormat(dayColumn, 7, name, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+}
@@ -0,0 +1,153 @@
+package timetable
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/utils"
+)
+
+var DayNames = [7]string{"周一", "周二", "周三", "周四", "周五", "周六", "周日"}
+
+// DayName 返回周期内第 day 天的名称，轮换模板会带上第几周，例如 "第 2 周周一"
+func DayName(day int32, rotationWeeks int32) string {
+	if day < 1 || day > 7*max(rotationWeeks, 1) {
+		return fmt.Sprintf("第 %d 天", day)
+	}
+	week, weekday := utils.SplitCycleDay(day)
+	if rotationWeeks <= 1 {
+		return DayNames[weekday-1]
+	}
+	return fmt.Sprintf("第 %d 周%s", week, DayNames[weekday-1])
+}
+
+// Cell 表示周表中某个 (shift, day) 格子
+type Cell struct {
+	Applicable bool     // 该班次在这一天是否需要值班
+	Principal  string   // 负责人姓名，为空表示没有负责人
+	Assistants []string // 其余助理的姓名
+}
+
+// Row 表示周表中的一行，即模板中的一个班次
+type Row struct {
+	Location  string // 值班点，为空表示默认值班点
+	StartTime string
+	EndTime   string  // 跨越午夜的班次带有 "次日" 前缀
+	Cells     [7]Cell // 下标 0 ~ 6 分别对应周一到周日
+}
+
+// Week 表示轮换周期中的一周，不轮换的模板只有一周
+type Week struct {
+	Name string // 轮换模板为 "第 N 周"，不轮换时为空
+	Rows []Row
+}
+
+// Timetable 是用于打印的周排班表，轮换模板的每一周单独成表
+type Timetable struct {
+	PlanName        string
+	ActiveStartDate string
+	ActiveEndDate   string
+	GeneratedAt     string
+	DayNames        [7]string
+	Weeks           []Week
+}
+
+// New 根据排班计划、模板、排班结果以及用户信息构建打印用的周排班表
+func New(plan *domain.SchedulePlan, template *domain.ScheduleTemplate, result *domain.SchedulingResult, users []*domain.User) *Timetable {
+	names := make(map[int64]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.FullName
+	}
+
+	// 排班结果中 (shiftID, day) -> item
+	items := make(map[int64]map[int32]domain.SchedulingResultShiftItem)
+	for _, shift := range result.Shifts {
+		items[shift.ShiftID] = make(map[int32]domain.SchedulingResultShiftItem)
+		for _, item := range shift.Items {
+			items[shift.ShiftID][item.Day] = item
+		}
+	}
+
+	// 班次按照值班点分组，同一个值班点内按照开始时间排序，使得周表从上到下按时间先后排列
+	shifts := slices.Clone(template.Shifts)
+	slices.SortFunc(shifts, func(a, b domain.ScheduleTemplateShift) int {
+		if c := strings.Compare(a.Location, b.Location); c != 0 {
+			return c
+		}
+		return strings.Compare(a.StartTime, b.StartTime)
+	})
+
+	rotationWeeks := max(template.RotationWeeks, 1)
+
+	tt := &Timetable{
+		PlanName:        plan.Name,
+		ActiveStartDate: plan.ActiveStartTime.Local().Format("2006-01-02"),
+		ActiveEndDate:   plan.ActiveEndTime.Local().Format("2006-01-02"),
+		GeneratedAt:     time.Now().Format("2006-01-02 15:04"),
+		DayNames:        DayNames,
+		Weeks:           make([]Week, 0, rotationWeeks),
+	}
+
+	for week := int32(1); week <= rotationWeeks; week++ {
+		w := Week{Rows: make([]Row, 0, len(shifts))}
+		if rotationWeeks > 1 {
+			w.Name = fmt.Sprintf("第 %d 周", week)
+		}
+		tt.Weeks = append(tt.Weeks, w)
+	}
+
+	for _, shift := range shifts {
+		// 每一周都有这一行，轮换模板中某一周完全不适用的班次也保留，方便对照
+		rows := make([]Row, rotationWeeks)
+		for i := range rows {
+			rows[i] = Row{
+				Location:  shift.Location,
+				StartTime: trimSeconds(shift.StartTime),
+				EndTime:   trimSeconds(shift.EndTime),
+			}
+			if utils.ShiftEndsNextDay(&shift) {
+				rows[i].EndTime = "次日 " + rows[i].EndTime
+			}
+		}
+
+		for _, day := range shift.ApplicableDays {
+			if day < 1 || day > 7*rotationWeeks {
+				continue
+			}
+			week, weekday := utils.SplitCycleDay(day)
+
+			cell := Cell{
+				Applicable: true,
+				Assistants: make([]string, 0),
+			}
+
+			if item, exists := items[shift.ID][day]; exists {
+				if item.PrincipalID != nil {
+					cell.Principal = names[*item.PrincipalID]
+				}
+				for _, assistantID := range item.AssistantIDs {
+					cell.Assistants = append(cell.Assistants, names[assistantID])
+				}
+			}
+
+			rows[week-1].Cells[weekday-1] = cell
+		}
+
+		for i, row := range rows {
+			tt.Weeks[i].Rows = append(tt.Weeks[i].Rows, row)
+		}
+	}
+
+	return tt
+}
+
+// trimSeconds 将 "15:04:05" 格式的时间去掉秒数，打印时更简洁
+func trimSeconds(t string) string {
+	if len(t) == len("15:04:05") {
+		return t[:len("15:04")]
+	}
+	return t
+}
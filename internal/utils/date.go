@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"math"
+	"time"
+)
+
+// DateOf 返回 t 在本地时区下的日期，以 UTC 零点表示，便于和数据库中的 DATE 比较
+func DateOf(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// DayOfWeek 将日期转换为排班中使用的星期，周一为 1，周日为 7
+func DayOfWeek(date time.Time) int32 {
+	if date.Weekday() == time.Sunday {
+		return 7
+	}
+	return int32(date.Weekday())
+}
+
+// AtClock 将日期和 "15:04:05" 格式的时刻组合成本地时区下的时间
+func AtClock(date time.Time, clock string) (time.Time, error) {
+	t, err := time.Parse("15:04:05", clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local), nil
+}
+
+// CycleDay 将轮换周期中的第几周（从 1 开始）和星期几组合成周期内的天数
+func CycleDay(week int32, weekday int32) int32 {
+	return (week-1)*7 + weekday
+}
+
+// SplitCycleDay 将周期内的天数拆分为第几周（从 1 开始）和星期几
+func SplitCycleDay(day int32) (int32, int32) {
+	return (day-1)/7 + 1, (day-1)%7 + 1
+}
+
+// RotationWeekOf 返回 date 在以 start 所在的周为第一周、每 weeks 周轮换一次的周期中是第几周（从 1 开始）
+func RotationWeekOf(start time.Time, date time.Time, weeks int32) int32 {
+	if weeks <= 1 {
+		return 1
+	}
+
+	// 只比较日期部分，避免时区和夏令时的影响
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	monday := start.AddDate(0, 0, -int(DayOfWeek(start)-1))
+	elapsed := int32(math.Floor(date.Sub(monday).Hours() / 24 / 7))
+	return (elapsed%weeks+weeks)%weeks + 1
+}
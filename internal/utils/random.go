@@ -122,8 +122,9 @@ func GenerateRandomApplicableDays() []int32 {
 
 func GenerateRandomScheduleTemplate() *domain.ScheduleTemplate {
 	st := domain.ScheduleTemplate{
-		Name:        "班表模板" + GenerateRandomID(3, 3),
-		Description: "班表模板描述" + GenerateRandomID(20, 10),
+		Name:          "班表模板" + GenerateRandomID(3, 3),
+		Description:   "班表模板描述" + GenerateRandomID(20, 10),
+		RotationWeeks: 1,
 	}
 
 	shiftsNum := rand.Intn(6) + 1
@@ -0,0 +1,295 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// resultReport 收集排班结果校验中发现的所有问题
+type resultReport struct {
+	report *domain.SchedulingResultValidationReport
+}
+
+func newResultReport() *resultReport {
+	return &resultReport{
+		report: &domain.SchedulingResultValidationReport{
+			Valid:      true,
+			Violations: make([]*domain.SchedulingResultViolation, 0),
+		},
+	}
+}
+
+// add 记录一个问题，shiftID、day、userID 为 0 表示和它们无关
+func (r *resultReport) add(severity domain.SchedulingResultViolationSeverity, code string, shiftID int64, day int32, userID int64, format string, args ...any) {
+	violation := &domain.SchedulingResultViolation{
+		Code:     code,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if shiftID != 0 {
+		violation.ShiftID = &shiftID
+	}
+	if day != 0 {
+		violation.Day = &day
+	}
+	if userID != 0 {
+		violation.UserID = &userID
+	}
+
+	switch severity {
+	case domain.SchedulingResultViolationSeverityError:
+		r.report.Valid = false
+		r.report.ErrorCount++
+	case domain.SchedulingResultViolationSeverityWarning:
+		r.report.WarningCount++
+	}
+	r.report.Violations = append(r.report.Violations, violation)
+}
+
+func (r *resultReport) error(code string, shiftID int64, day int32, userID int64, format string, args ...any) {
+	r.add(domain.SchedulingResultViolationSeverityError, code, shiftID, day, userID, format, args...)
+}
+
+func (r *resultReport) warning(code string, shiftID int64, day int32, userID int64, format string, args ...any) {
+	r.add(domain.SchedulingResultViolationSeverityWarning, code, shiftID, day, userID, format, args...)
+}
+
+// err 返回第一个错误，只有警告时返回 nil
+func (r *resultReport) err() error {
+	for _, violation := range r.report.Violations {
+		if violation.Severity == domain.SchedulingResultViolationSeverityError {
+			return errors.New(violation.Message)
+		}
+	}
+	return nil
+}
+
+// itemUserIDs 返回 (shift, day) 中安排的所有人，负责人排在最前面
+// 重复出现的人只返回一次，重复本身由 checkDuplicateAssistant 报告，避免同一个问题被报告多次
+func itemUserIDs(item domain.SchedulingResultShiftItem) []int64 {
+	userIDs := make([]int64, 0, len(item.AssistantIDs)+1)
+	if item.PrincipalID != nil {
+		userIDs = append(userIDs, *item.PrincipalID)
+	}
+	for _, assistantID := range item.AssistantIDs {
+		if !slices.Contains(userIDs, assistantID) {
+			userIDs = append(userIDs, assistantID)
+		}
+	}
+	return userIDs
+}
+
+// ValidateSchedulingResult 对排班结果做完整的校验，返回所有发现的问题而不是只返回第一个
+// users 用于检查负责人的身份，participants 为空或者没有设置名单时不检查参与名单
+func ValidateSchedulingResult(
+	result *domain.SchedulingResult,
+	template *domain.ScheduleTemplate,
+	submissions []*domain.AvailabilitySubmission,
+	users map[int64]*domain.User,
+	participants *domain.SchedulePlanParticipants,
+) *domain.SchedulingResultValidationReport {
+	report := newResultReport()
+	checkSchedulingResultWithTemplate(report, result, template)
+	checkSchedulingResultPrincipals(report, result, users)
+	checkSchedulingResultWithSubmissions(report, result, submissions)
+	if participants != nil && participants.Restricted {
+		checkSchedulingResultParticipants(report, result, participants.Users)
+	}
+	checkDuplicateAssistant(report, result)
+	checkSchedulingResultOverlaps(report, result, template)
+	return report.report
+}
+
+func ValidateSchedulingResultWithSubmissions(result *domain.SchedulingResult, submissions []*domain.AvailabilitySubmission) error {
+	report := newResultReport()
+	checkSchedulingResultWithSubmissions(report, result, submissions)
+	return report.err()
+}
+
+func ValidIfExistsDuplicateAssistant(result *domain.SchedulingResult) error {
+	report := newResultReport()
+	checkDuplicateAssistant(report, result)
+	return report.err()
+}
+
+// ValidateSchedulingResultOverlaps 检查是否有人被安排到时间重叠的班次中（例如不同值班点同时进行的班次，或者跨越午夜的班次和第二天早上的班次）
+func ValidateSchedulingResultOverlaps(result *domain.SchedulingResult, template *domain.ScheduleTemplate) error {
+	report := newResultReport()
+	checkSchedulingResultOverlaps(report, result, template)
+	return report.err()
+}
+
+func checkSchedulingResultWithTemplate(report *resultReport, result *domain.SchedulingResult, template *domain.ScheduleTemplate) {
+	seen := make(map[int64]bool)
+
+	for _, resultShift := range result.Shifts {
+		// 找到模板中对应的班次
+		index := slices.IndexFunc(template.Shifts, func(shift domain.ScheduleTemplateShift) bool { return shift.ID == resultShift.ShiftID })
+		if index == -1 {
+			report.error(domain.SchedulingResultViolationUnknownShift, resultShift.ShiftID, 0, 0, "排班结果中的第 %d 项不存在于排班模板中", resultShift.ShiftID)
+			continue
+		}
+		templateShift := &template.Shifts[index]
+
+		if seen[resultShift.ShiftID] {
+			report.error(domain.SchedulingResultViolationDuplicateShift, resultShift.ShiftID, 0, 0, "排班结果中的第 %d 项重复出现", resultShift.ShiftID)
+			continue
+		}
+		seen[resultShift.ShiftID] = true
+
+		for _, day := range templateShift.ApplicableDays {
+			if !slices.ContainsFunc(resultShift.Items, func(item domain.SchedulingResultShiftItem) bool { return item.Day == day }) {
+				report.error(domain.SchedulingResultViolationMissingDay, resultShift.ShiftID, day, 0, "排班结果中的第 %d 项的班次存在没有提交结果的天数 %d", resultShift.ShiftID, day)
+			}
+		}
+
+		for _, item := range resultShift.Items {
+			if !slices.Contains(templateShift.ApplicableDays, item.Day) {
+				report.error(domain.SchedulingResultViolationInapplicableDay, resultShift.ShiftID, item.Day, 0, "排班结果中的第 %d 项的第 %d 天不符合模板中的班次", resultShift.ShiftID, item.Day)
+				continue
+			}
+
+			requiredNumber, principalRequired := templateShift.RequirementOn(item.Day)
+			// 需要负责人的时候负责人也算一个助理，不需要负责人的时候只有实际安排了负责人才算
+			staffNumber := len(item.AssistantIDs)
+			if principalRequired || item.PrincipalID != nil {
+				staffNumber++
+			}
+			if staffNumber > int(requiredNumber) {
+				report.error(domain.SchedulingResultViolationOverstaffed, resultShift.ShiftID, item.Day, 0, "排班结果中的第 %d 项的第 %d 天的助理人数超过了模板中的要求", resultShift.ShiftID, item.Day)
+			}
+			// 人手不足只是警告，允许黑心先保存再慢慢补人
+			if assigned := len(itemUserIDs(item)); assigned < int(requiredNumber) {
+				report.warning(domain.SchedulingResultViolationUnderstaffed, resultShift.ShiftID, item.Day, 0, "排班结果中的第 %d 项的第 %d 天只安排了 %d 人，少于模板要求的 %d 人", resultShift.ShiftID, item.Day, assigned, requiredNumber)
+			}
+
+			if principalRequired && item.PrincipalID == nil {
+				report.warning(domain.SchedulingResultViolationMissingPrincipal, resultShift.ShiftID, item.Day, 0, "排班结果中的第 %d 项的第 %d 天没有安排负责人", resultShift.ShiftID, item.Day)
+			}
+		}
+	}
+
+	for _, shift := range template.Shifts {
+		if !seen[shift.ID] {
+			report.error(domain.SchedulingResultViolationMissingShift, shift.ID, 0, 0, "模板中的班次 %d 没有出现在排班结果中", shift.ID)
+		}
+	}
+}
+
+// checkSchedulingResultPrincipals 检查负责人是否都是能够担任负责人的身份（资深助理或者黑心）
+func checkSchedulingResultPrincipals(report *resultReport, result *domain.SchedulingResult, users map[int64]*domain.User) {
+	for _, resultShift := range result.Shifts {
+		for _, item := range resultShift.Items {
+			if item.PrincipalID == nil {
+				continue
+			}
+			user, exists := users[*item.PrincipalID]
+			if !exists {
+				continue
+			}
+			if user.Role != domain.RoleSeniorAssistant && user.Role != domain.RoleBlackCore {
+				report.warning(domain.SchedulingResultViolationIneligiblePrincipal, resultShift.ShiftID, item.Day, user.ID, "%s 是%s，不能担任班次 %d 的第 %d 天的负责人", user.FullName, user.Role, resultShift.ShiftID, item.Day)
+			}
+		}
+	}
+}
+
+func checkSchedulingResultWithSubmissions(report *resultReport, result *domain.SchedulingResult, submissions []*domain.AvailabilitySubmission) {
+	submissionsByUser := make(map[int64]*domain.AvailabilitySubmission, len(submissions))
+	for _, submission := range submissions {
+		submissionsByUser[submission.UserID] = submission
+	}
+
+	for _, shift := range result.Shifts {
+		for _, item := range shift.Items {
+			for _, userID := range itemUserIDs(item) {
+				position := "助理"
+				if item.PrincipalID != nil && *item.PrincipalID == userID {
+					position = "负责人"
+				}
+
+				submission, exists := submissionsByUser[userID]
+				if !exists {
+					report.error(domain.SchedulingResultViolationNoSubmission, shift.ShiftID, item.Day, userID, "班次 %d 的第 %d 天的 id 为 %d 的%s没有提交空闲时间", shift.ShiftID, item.Day, userID, position)
+					continue
+				}
+
+				// 检查这个人是否在第 item.Day 天有空闲时间
+				available := slices.ContainsFunc(submission.Items, func(submissionItem domain.AvailabilitySubmissionItem) bool {
+					return submissionItem.ShiftID == shift.ShiftID && slices.Contains(submissionItem.Days, item.Day)
+				})
+				if !available {
+					report.error(domain.SchedulingResultViolationUnavailable, shift.ShiftID, item.Day, userID, "id 为 %d 的%s在班次 %d 的第 %d 天没有空闲时间", userID, position, shift.ShiftID, item.Day)
+				}
+			}
+		}
+	}
+}
+
+func checkSchedulingResultParticipants(report *resultReport, result *domain.SchedulingResult, participants []*domain.User) {
+	for _, resultShift := range result.Shifts {
+		for _, item := range resultShift.Items {
+			for _, userID := range itemUserIDs(item) {
+				if !slices.ContainsFunc(participants, func(user *domain.User) bool { return user.ID == userID }) {
+					report.error(domain.SchedulingResultViolationNotParticipant, resultShift.ShiftID, item.Day, userID, "排班结果中的第 %d 项的第 %d 天安排的 id 为 %d 的助理不在参与名单中", resultShift.ShiftID, item.Day, userID)
+				}
+			}
+		}
+	}
+}
+
+func checkDuplicateAssistant(report *resultReport, result *domain.SchedulingResult) {
+	// 检查是否存在某个班次中的某一天有重复的助理
+	for _, resultShift := range result.Shifts {
+		for _, item := range resultShift.Items {
+			// 先检查负责人是不是存在于助理数组中
+			if item.PrincipalID != nil && slices.Contains(item.AssistantIDs, *item.PrincipalID) {
+				report.error(domain.SchedulingResultViolationDuplicateAssistant, resultShift.ShiftID, item.Day, *item.PrincipalID, "班次 %d 的第 %d 天中负责人和助理重复", resultShift.ShiftID, item.Day)
+			}
+			// 检查助理之间是否有重复
+			seen := make(map[int64]bool)
+			for _, assistantID := range item.AssistantIDs {
+				if seen[assistantID] {
+					report.error(domain.SchedulingResultViolationDuplicateAssistant, resultShift.ShiftID, item.Day, assistantID, "班次 %d 的第 %d 天中存在重复助理", resultShift.ShiftID, item.Day)
+				}
+				seen[assistantID] = true
+			}
+		}
+	}
+}
+
+func checkSchedulingResultOverlaps(report *resultReport, result *domain.SchedulingResult, template *domain.ScheduleTemplate) {
+	shifts := make(map[int64]*domain.ScheduleTemplateShift, len(template.Shifts))
+	for i := range template.Shifts {
+		shifts[template.Shifts[i].ID] = &template.Shifts[i]
+	}
+
+	type assignment struct {
+		shift *domain.ScheduleTemplateShift
+		day   int32
+	}
+	assignments := make(map[int64][]assignment) // userID -> 这个人的所有安排
+
+	for _, resultShift := range result.Shifts {
+		shift, exists := shifts[resultShift.ShiftID]
+		if !exists {
+			continue
+		}
+		for _, item := range resultShift.Items {
+			for _, userID := range itemUserIDs(item) {
+				for _, other := range assignments[userID] {
+					if other.shift.ID == shift.ID && other.day == item.Day {
+						continue
+					}
+					if ScheduleTemplateShiftsOverlapOn(other.shift, other.day, shift, item.Day, template.RotationWeeks) {
+						report.error(domain.SchedulingResultViolationOverlap, shift.ID, item.Day, userID, "id 为 %d 的助理被同时安排到了时间重叠的班次 %d（第 %d 天）和班次 %d（第 %d 天）", userID, other.shift.ID, other.day, shift.ID, item.Day)
+					}
+				}
+				assignments[userID] = append(assignments[userID], assignment{shift: shift, day: item.Day})
+			}
+		}
+	}
+}
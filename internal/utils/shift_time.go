@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+const week = 7 * 24 * time.Hour
+
+// clockOf 返回 t 的时刻距离当天零点的时长
+func clockOf(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// ShiftClockRange 返回班次的开始和结束时刻距离开始当天零点的时长
+// 结束时间早于开始时间的班次跨越午夜、在第二天结束（例如 22:00-02:00），此时结束时刻会超过 24 小时
+func ShiftClockRange(shift *domain.ScheduleTemplateShift) (time.Duration, time.Duration, error) {
+	startTime, err := time.Parse("15:04:05", shift.StartTime)
+	if err != nil {
+		return 0, 0, err
+	}
+	endTime, err := time.Parse("15:04:05", shift.EndTime)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start, end := clockOf(startTime), clockOf(endTime)
+	if end < start {
+		end += 24 * time.Hour
+	}
+	return start, end, nil
+}
+
+// ShiftEndsNextDay 判断班次是否跨越午夜、在第二天结束
+func ShiftEndsNextDay(shift *domain.ScheduleTemplateShift) bool {
+	_, end, err := ShiftClockRange(shift)
+	return err == nil && end > 24*time.Hour
+}
+
+// ShiftDuration 返回班次的时长，时间格式错误时返回 0
+func ShiftDuration(shift *domain.ScheduleTemplateShift) time.Duration {
+	start, end, err := ShiftClockRange(shift)
+	if err != nil {
+		return 0
+	}
+	return end - start
+}
+
+// ScheduleTemplateShiftsOverlapOn 判断班次 a 在周期内第 aDay 天的安排和班次 b 在第 bDay 天的安排时间是否重叠，首尾相接不算重叠
+// 跨越午夜的班次会占用第二天的前一段时间，周期最后一天的班次会延续到下一个周期的第一天
+func ScheduleTemplateShiftsOverlapOn(a *domain.ScheduleTemplateShift, aDay int32, b *domain.ScheduleTemplateShift, bDay int32, rotationWeeks int32) bool {
+	aStart, aEnd, err := ShiftClockRange(a)
+	if err != nil {
+		return false
+	}
+	bStart, bEnd, err := ShiftClockRange(b)
+	if err != nil {
+		return false
+	}
+
+	// 以周期第一天零点为起点，把两个安排放到同一条时间轴上
+	aOffset := time.Duration(aDay-1) * 24 * time.Hour
+	bOffset := time.Duration(bDay-1) * 24 * time.Hour
+	aStart, aEnd = aStart+aOffset, aEnd+aOffset
+
+	cycle := week * time.Duration(max(rotationWeeks, 1))
+	for _, wrap := range []time.Duration{-cycle, 0, cycle} {
+		if aStart < bEnd+bOffset+wrap && bStart+bOffset+wrap < aEnd {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"slices"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// MapSubmissionToTemplate 将按照旧模板提交的空闲时间对应到新模板上
+// 开始时间、结束时间和值班点都相同的班次视为同一个班次，并且新班次在当天也必须开放
+// 返回的 items 和新模板的班次一一对应，可以直接作为新的提交；无法对应的 (shift, day) 放在 unmapped 中
+func MapSubmissionToTemplate(submission *domain.AvailabilitySubmission, from *domain.ScheduleTemplate, to *domain.ScheduleTemplate) ([]domain.AvailabilitySubmissionItem, []domain.UnmappedSubmissionSlot) {
+	items := make([]domain.AvailabilitySubmissionItem, len(to.Shifts))
+	for i, shift := range to.Shifts {
+		items[i] = domain.AvailabilitySubmissionItem{ShiftID: shift.ID, Days: make([]int32, 0)}
+	}
+	unmapped := make([]domain.UnmappedSubmissionSlot, 0)
+
+	for _, item := range submission.Items {
+		i := slices.IndexFunc(from.Shifts, func(shift domain.ScheduleTemplateShift) bool { return shift.ID == item.ShiftID })
+		if i < 0 {
+			// 旧模板中的班次已经不存在了，没有时间信息可以对应
+			for _, day := range item.Days {
+				unmapped = append(unmapped, domain.UnmappedSubmissionSlot{ShiftID: item.ShiftID, Day: day, Reason: "原模板中已不存在该班次"})
+			}
+			continue
+		}
+		oldShift := from.Shifts[i]
+
+		sameShift := func(shift domain.ScheduleTemplateShift) bool {
+			return shift.StartTime == oldShift.StartTime && shift.EndTime == oldShift.EndTime && shift.Location == oldShift.Location
+		}
+		exists := slices.ContainsFunc(to.Shifts, sameShift)
+
+		for _, day := range item.Days {
+			slot := domain.UnmappedSubmissionSlot{
+				ShiftID:   oldShift.ID,
+				StartTime: oldShift.StartTime,
+				EndTime:   oldShift.EndTime,
+				Location:  oldShift.Location,
+				Day:       day,
+			}
+
+			// 同一个值班点可能有多个时间相同、适用天数不同的班次，需要按天找
+			j := slices.IndexFunc(to.Shifts, func(shift domain.ScheduleTemplateShift) bool {
+				return sameShift(shift) && slices.Contains(shift.ApplicableDays, day)
+			})
+			switch {
+			case !exists:
+				slot.Reason = "新模板中没有时间和值班点都相同的班次"
+				unmapped = append(unmapped, slot)
+			case j < 0:
+				slot.Reason = "新模板中该班次当天不开放"
+				unmapped = append(unmapped, slot)
+			case !slices.Contains(items[j].Days, day):
+				items[j].Days = append(items[j].Days, day)
+			}
+		}
+	}
+
+	for i := range items {
+		slices.Sort(items[i].Days)
+	}
+
+	return items, unmapped
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+type shiftDay struct {
+	shiftID int64
+	day     int32
+}
+
+func availableShiftDays(submission *domain.AvailabilitySubmission) map[shiftDay]bool {
+	set := make(map[shiftDay]bool)
+	if submission == nil {
+		return set
+	}
+	for _, item := range submission.Items {
+		for _, day := range item.Days {
+			set[shiftDay{item.ShiftID, day}] = true
+		}
+	}
+	return set
+}
+
+// groupShiftDays 将 (shift, day) 按照班次分组，班次和天数都按照升序排列
+func groupShiftDays(shiftDays []shiftDay) []domain.AvailabilitySubmissionItem {
+	slices.SortFunc(shiftDays, func(a, b shiftDay) int {
+		return cmp.Or(cmp.Compare(a.shiftID, b.shiftID), cmp.Compare(a.day, b.day))
+	})
+
+	items := make([]domain.AvailabilitySubmissionItem, 0)
+	for _, sd := range shiftDays {
+		if len(items) == 0 || items[len(items)-1].ShiftID != sd.shiftID {
+			items = append(items, domain.AvailabilitySubmissionItem{ShiftID: sd.shiftID, Days: make([]int32, 0)})
+		}
+		items[len(items)-1].Days = append(items[len(items)-1].Days, sd.day)
+	}
+	return items
+}
+
+// DiffAvailabilitySubmissions 比较两次提交之间新增和删除了哪些 (shift, day)，prev 为 nil 时所有班次都视为新增
+func DiffAvailabilitySubmissions(prev *domain.AvailabilitySubmission, next *domain.AvailabilitySubmission) domain.AvailabilitySubmissionDiff {
+	before := availableShiftDays(prev)
+	after := availableShiftDays(next)
+
+	added := make([]shiftDay, 0)
+	for sd := range after {
+		if !before[sd] {
+			added = append(added, sd)
+		}
+	}
+	removed := make([]shiftDay, 0)
+	for sd := range before {
+		if !after[sd] {
+			removed = append(removed, sd)
+		}
+	}
+
+	return domain.AvailabilitySubmissionDiff{
+		Added:   groupShiftDays(added),
+		Removed: groupShiftDays(removed),
+	}
+}
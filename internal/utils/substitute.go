@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// CountScheduledShifts 统计每个用户在排班结果中每周的班次数
+func CountScheduledShifts(result *domain.SchedulingResult) map[int64]int {
+	counts := make(map[int64]int)
+	for _, shift := range result.Shifts {
+		for _, item := range shift.Items {
+			if item.PrincipalID != nil {
+				counts[*item.PrincipalID]++
+			}
+			for _, assistantID := range item.AssistantIDs {
+				counts[assistantID]++
+			}
+		}
+	}
+	return counts
+}
+
+// IsPrincipalOf 判断某个用户是否是排班结果中 (shift, day) 的负责人
+func IsPrincipalOf(result *domain.SchedulingResult, shiftID int64, day int32, userID int64) bool {
+	item := findSchedulingResultShiftItem(result, shiftID, day)
+	return item != nil && item.PrincipalID != nil && *item.PrincipalID == userID
+}
+
+// SubstituteOverlapsDuty 判断 userID 在请假当天以及前后一天已有的值班或者代班中，是否有和请假的班次时间重叠的
+// 前一天跨越午夜的班次也会和请假当天的班次重叠；userID 自己已经请假（且已批准）的值班不算
+// dayOf 返回某个日期按照周期内的哪一天排班，第二个返回值为 false 时表示当天停班
+func SubstituteOverlapsDuty(
+	leave *domain.LeaveRequest,
+	template *domain.ScheduleTemplate,
+	result *domain.SchedulingResult,
+	leaves []*domain.LeaveRequest,
+	userID int64,
+	dayOf func(date time.Time) (int32, bool),
+) bool {
+	findShift := func(shiftID int64) *domain.ScheduleTemplateShift {
+		i := slices.IndexFunc(template.Shifts, func(shift domain.ScheduleTemplateShift) bool { return shift.ID == shiftID })
+		if i < 0 {
+			return nil
+		}
+		return &template.Shifts[i]
+	}
+	onLeave := func(shiftID int64, date time.Time) bool {
+		return slices.ContainsFunc(leaves, func(l *domain.LeaveRequest) bool {
+			return l.UserID == userID && l.Status == domain.LeaveRequestStatusApproved && l.ShiftID == shiftID && l.Date.Equal(date)
+		})
+	}
+
+	leaveShift := findShift(leave.ShiftID)
+	if leaveShift == nil {
+		return false
+	}
+	// 请假当天放在第 2 天，前后一天分别是第 1、3 天，只比较相对的先后关系
+	overlaps := func(shiftID int64, offset int) bool {
+		shift := findShift(shiftID)
+		return shift != nil && ScheduleTemplateShiftsOverlapOn(leaveShift, 2, shift, int32(2+offset), template.RotationWeeks)
+	}
+
+	for offset := -1; offset <= 1; offset++ {
+		date := leave.Date.AddDate(0, 0, offset)
+
+		if day, ok := dayOf(date); ok {
+			for _, resultShift := range result.Shifts {
+				if !IsScheduledIn(result, resultShift.ShiftID, day, userID) || onLeave(resultShift.ShiftID, date) {
+					continue
+				}
+				if overlaps(resultShift.ShiftID, offset) {
+					return true
+				}
+			}
+		}
+
+		for _, l := range leaves {
+			if l.ID == leave.ID || l.Status != domain.LeaveRequestStatusApproved || l.SubstituteID == nil || *l.SubstituteID != userID {
+				continue
+			}
+			if l.Date.Equal(date) && overlaps(l.ShiftID, offset) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// RankSubstituteCandidates 为请假申请找出可以代班的人，并按照当前的工作量从低到高排序
+// day 为请假当天实际按照周期内的哪一天排班（调休时可能和日期本身的星期不同）
+// 候选人必须在空闲时间提交中选择了该星期的该班次，且当天没有在该班次值班或者代班，也没有时间重叠的其它值班或者代班
+// 如果请假的人是该班次的负责人，则候选人还必须具有担任负责人的资格
+func RankSubstituteCandidates(
+	leave *domain.LeaveRequest,
+	day int32,
+	template *domain.ScheduleTemplate,
+	result *domain.SchedulingResult,
+	submissions []*domain.AvailabilitySubmission,
+	users []*domain.User,
+	leaves []*domain.LeaveRequest,
+	dayOf func(date time.Time) (int32, bool),
+) []*domain.SubstituteCandidate {
+	needPrincipal := IsPrincipalOf(result, leave.ShiftID, day, leave.UserID)
+
+	available := make(map[int64]bool)
+	for _, submission := range submissions {
+		for _, item := range submission.Items {
+			if item.ShiftID == leave.ShiftID && slices.Contains(item.Days, day) {
+				available[submission.UserID] = true
+			}
+		}
+	}
+
+	// 统计已批准的请假中每个人代班的次数，并找出当天该班次已经在代班的人
+	substituteCounts := make(map[int64]int)
+	busy := make(map[int64]bool)
+	for _, l := range leaves {
+		if l.Status != domain.LeaveRequestStatusApproved || l.SubstituteID == nil || l.ID == leave.ID {
+			continue
+		}
+		substituteCounts[*l.SubstituteID]++
+		if l.ShiftID == leave.ShiftID && l.Date.Equal(leave.Date) {
+			busy[*l.SubstituteID] = true
+		}
+	}
+
+	scheduledCounts := CountScheduledShifts(result)
+
+	candidates := make([]*domain.SubstituteCandidate, 0)
+	for _, user := range users {
+		if user.ID == leave.UserID || !user.IsActive || !available[user.ID] || busy[user.ID] {
+			continue
+		}
+		if IsScheduledIn(result, leave.ShiftID, day, user.ID) {
+			continue
+		}
+		if needPrincipal && user.Role != domain.RoleSeniorAssistant && user.Role != domain.RoleBlackCore {
+			continue
+		}
+		if SubstituteOverlapsDuty(leave, template, result, leaves, user.ID, dayOf) {
+			continue
+		}
+
+		candidates = append(candidates, &domain.SubstituteCandidate{
+			UserID:          user.ID,
+			Username:        user.Username,
+			FullName:        user.FullName,
+			Role:            user.Role,
+			ScheduledCount:  scheduledCounts[user.ID],
+			SubstituteCount: substituteCounts[user.ID],
+			Load:            scheduledCounts[user.ID] + substituteCounts[user.ID],
+		})
+	}
+
+	slices.SortStableFunc(candidates, func(a, b *domain.SubstituteCandidate) int {
+		if a.Load != b.Load {
+			return cmp.Compare(a.Load, b.Load)
+		}
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+
+	return candidates
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+func findSchedulingResultShiftItem(result *domain.SchedulingResult, shiftID int64, day int32) *domain.SchedulingResultShiftItem {
+	for i := range result.Shifts {
+		if result.Shifts[i].ShiftID != shiftID {
+			continue
+		}
+		for j := range result.Shifts[i].Items {
+			if result.Shifts[i].Items[j].Day == day {
+				return &result.Shifts[i].Items[j]
+			}
+		}
+	}
+	return nil
+}
+
+// IsScheduledIn 判断某个用户是否在排班结果的 (shift, day) 中值班（无论是负责人还是助理）
+func IsScheduledIn(result *domain.SchedulingResult, shiftID int64, day int32, userID int64) bool {
+	item := findSchedulingResultShiftItem(result, shiftID, day)
+	if item == nil {
+		return false
+	}
+	return (item.PrincipalID != nil && *item.PrincipalID == userID) || slices.Contains(item.AssistantIDs, userID)
+}
+
+// replaceInSchedulingResultShiftItem 将 (shift, day) 中的 from 替换为 to
+// 如果 from 是负责人，则 to 必须具有担任负责人的资格
+func replaceInSchedulingResultShiftItem(item *domain.SchedulingResultShiftItem, from *domain.User, to *domain.User) error {
+	if item.PrincipalID != nil && *item.PrincipalID == from.ID {
+		if to.Role != domain.RoleSeniorAssistant && to.Role != domain.RoleBlackCore {
+			return fmt.Errorf("%s 是该班次的负责人，而 %s 不具备担任负责人的资格", from.FullName, to.FullName)
+		}
+		id := to.ID
+		item.PrincipalID = &id
+		return nil
+	}
+
+	i := slices.Index(item.AssistantIDs, from.ID)
+	if i < 0 {
+		return fmt.Errorf("%s 不在该班次中", from.FullName)
+	}
+	item.AssistantIDs[i] = to.ID
+	return nil
+}
+
+// ApplyShiftSwap 将换班申请应用到排班结果上（直接修改 result），并检查换班后的排班结果是否合法
+// template 是排班计划使用的模板，用于检查换班后是否有人被安排到时间重叠的班次中
+func ApplyShiftSwap(result *domain.SchedulingResult, template *domain.ScheduleTemplate, swap *domain.ShiftSwap, proposer *domain.User, counterparty *domain.User) error {
+	if !counterparty.IsActive {
+		return fmt.Errorf("%s 已离职", counterparty.FullName)
+	}
+
+	item := findSchedulingResultShiftItem(result, swap.ShiftID, swap.Day)
+	if item == nil {
+		return errors.New("排班结果中不存在该班次")
+	}
+	if !IsScheduledIn(result, swap.ShiftID, swap.Day, proposer.ID) {
+		return fmt.Errorf("%s 已不在该班次中", proposer.FullName)
+	}
+	if IsScheduledIn(result, swap.ShiftID, swap.Day, counterparty.ID) {
+		return fmt.Errorf("%s 已经在该班次中", counterparty.FullName)
+	}
+
+	switch swap.Type {
+	case domain.ShiftSwapTypeGive:
+		if err := replaceInSchedulingResultShiftItem(item, proposer, counterparty); err != nil {
+			return err
+		}
+	case domain.ShiftSwapTypeExchange:
+		if swap.CounterpartyShiftID == nil || swap.CounterpartyDay == nil {
+			return errors.New("交换班次时必须指定对方的班次")
+		}
+
+		counterpartyItem := findSchedulingResultShiftItem(result, *swap.CounterpartyShiftID, *swap.CounterpartyDay)
+		if counterpartyItem == nil {
+			return errors.New("排班结果中不存在对方的班次")
+		}
+		if counterpartyItem == item {
+			return errors.New("不能和同一个班次中的助理交换")
+		}
+		if !IsScheduledIn(result, *swap.CounterpartyShiftID, *swap.CounterpartyDay, counterparty.ID) {
+			return fmt.Errorf("%s 已不在对方的班次中", counterparty.FullName)
+		}
+		if IsScheduledIn(result, *swap.CounterpartyShiftID, *swap.CounterpartyDay, proposer.ID) {
+			return fmt.Errorf("%s 已经在对方的班次中", proposer.FullName)
+		}
+
+		if err := replaceInSchedulingResultShiftItem(item, proposer, counterparty); err != nil {
+			return err
+		}
+		if err := replaceInSchedulingResultShiftItem(counterpartyItem, counterparty, proposer); err != nil {
+			return err
+		}
+	default:
+		return errors.New("未知的换班类型")
+	}
+
+	// 最后再整体检查一遍是否存在重复的助理，以及是否有人被安排到时间重叠的班次中
+	if err := ValidIfExistsDuplicateAssistant(result); err != nil {
+		return err
+	}
+	return ValidateSchedulingResultOverlaps(result, template)
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/sysu-ecnc-dev/shift-manager/backend/internal/domain"
+)
+
+// DiffScheduleTemplateShifts 比较模板现有的班次和修改后的班次，ID 为 0 的班次视为新增
+func DiffScheduleTemplateShifts(template *domain.ScheduleTemplate, shifts []domain.ScheduleTemplateShift) (*domain.ScheduleTemplateChanges, error) {
+	changes := &domain.ScheduleTemplateChanges{
+		Added:    make([]domain.ScheduleTemplateShift, 0),
+		Removed:  make([]domain.ScheduleTemplateShift, 0),
+		Modified: make([]domain.ScheduleTemplateShiftChange, 0),
+	}
+
+	kept := make(map[int64]bool)
+	for _, shift := range shifts {
+		if shift.ID == 0 {
+			changes.Added = append(changes.Added, shift)
+			continue
+		}
+
+		i := slices.IndexFunc(template.Shifts, func(s domain.ScheduleTemplateShift) bool { return s.ID == shift.ID })
+		if i < 0 {
+			return nil, fmt.Errorf("班次 %d 不属于该模板", shift.ID)
+		}
+		if kept[shift.ID] {
+			return nil, fmt.Errorf("班次 %d 重复出现", shift.ID)
+		}
+		kept[shift.ID] = true
+
+		before := template.Shifts[i]
+		change := domain.ScheduleTemplateShiftChange{
+			Before:      before,
+			After:       shift,
+			AddedDays:   make([]int32, 0),
+			RemovedDays: make([]int32, 0),
+		}
+		for _, day := range shift.ApplicableDays {
+			if !slices.Contains(before.ApplicableDays, day) && !slices.Contains(change.AddedDays, day) {
+				change.AddedDays = append(change.AddedDays, day)
+			}
+		}
+		for _, day := range before.ApplicableDays {
+			if !slices.Contains(shift.ApplicableDays, day) {
+				change.RemovedDays = append(change.RemovedDays, day)
+			}
+		}
+		slices.Sort(change.AddedDays)
+		slices.Sort(change.RemovedDays)
+
+		if before.StartTime == shift.StartTime &&
+			before.EndTime == shift.EndTime &&
+			before.Location == shift.Location &&
+			before.RequiredAssistantNumber == shift.RequiredAssistantNumber &&
+			sameDayRequirements(before.DayRequirements, shift.DayRequirements) &&
+			len(change.AddedDays) == 0 && len(change.RemovedDays) == 0 {
+			continue
+		}
+		changes.Modified = append(changes.Modified, change)
+	}
+
+	for _, shift := range template.Shifts {
+		if !kept[shift.ID] {
+			changes.Removed = append(changes.Removed, shift)
+		}
+	}
+
+	return changes, nil
+}
+
+// sameDayRequirements 判断两组按天的人数要求是否相同，与顺序无关
+func sameDayRequirements(a, b []domain.ScheduleTemplateShiftDayRequirement) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, requirement := range a {
+		if !slices.Contains(b, requirement) {
+			return false
+		}
+	}
+	return true
+}
+
+// RemovedScheduleTemplateSlots 返回修改后不再存在的 (shift, day)，以两个等长的数组表示，方便直接传给数据库
+func RemovedScheduleTemplateSlots(changes *domain.ScheduleTemplateChanges) ([]int64, []int32) {
+	shiftIDs := make([]int64, 0)
+	days := make([]int32, 0)
+
+	for _, shift := range changes.Removed {
+		for _, day := range shift.ApplicableDays {
+			shiftIDs = append(shiftIDs, shift.ID)
+			days = append(days, day)
+		}
+	}
+	for _, change := range changes.Modified {
+		for _, day := range change.RemovedDays {
+			shiftIDs = append(shiftIDs, change.Before.ID)
+			days = append(days, day)
+		}
+	}
+
+	return shiftIDs, days
+}
+
+// ChangedScheduleTemplateShiftIDs 返回被删除或者被修改的班次
+func ChangedScheduleTemplateShiftIDs(changes *domain.ScheduleTemplateChanges) []int64 {
+	ids := make([]int64, 0, len(changes.Removed)+len(changes.Modified))
+	for _, shift := range changes.Removed {
+		ids = append(ids, shift.ID)
+	}
+	for _, change := range changes.Modified {
+		ids = append(ids, change.Before.ID)
+	}
+	return ids
+}
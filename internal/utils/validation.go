@@ -10,32 +10,66 @@ import (
 )
 
 func ValidateScheduleTemplateShiftTime(st *domain.ScheduleTemplate) error {
-	// 检查每一个班次的结束时间是不是都大于开始时间
+	// 轮换模板的适用天数是周期内的天数，不能超出周期的长度
+	if st.RotationWeeks < 1 {
+		return errors.New("轮换周期至少为 1 周")
+	}
+	cycleDays := 7 * st.RotationWeeks
 	for id, shift := range st.Shifts {
-		startTime, err := time.Parse("15:04:05", shift.StartTime)
-		if err != nil {
+		for _, day := range shift.ApplicableDays {
+			if day < 1 || day > cycleDays {
+				return fmt.Errorf("班次 %d 的适用天数 %d 超出了轮换周期（1-%d）", id, day, cycleDays)
+			}
+		}
+	}
+
+	// 检查每一个班次的时间格式，结束时间早于开始时间表示班次跨越午夜、在第二天结束
+	for id, shift := range st.Shifts {
+		if _, err := time.Parse("15:04:05", shift.StartTime); err != nil {
 			return fmt.Errorf("班次 %d 的开始时间格式错误", id)
 		}
-		endTime, err := time.Parse("15:04:05", shift.EndTime)
-		if err != nil {
+		if _, err := time.Parse("15:04:05", shift.EndTime); err != nil {
 			return fmt.Errorf("班次 %d 的结束时间格式错误", id)
 		}
-		if endTime.Before(startTime) {
-			return fmt.Errorf("班次 %d 的结束时间不能小于开始时间", id)
+		if ShiftDuration(&shift) == 0 {
+			return fmt.Errorf("班次 %d 的结束时间不能等于开始时间", id)
 		}
 	}
 
-	// 检查各个班次之间的时间是否冲突
+	// 检查同一个值班点的各个班次之间的时间是否冲突，不同值班点的班次可以同时进行
+	// 跨越午夜的班次还会和第二天开始的班次比较
 	for i := 0; i < len(st.Shifts); i++ {
-		iStartTime, _ := time.Parse("15:04:05", st.Shifts[i].StartTime)
-		iEndTime, _ := time.Parse("15:04:05", st.Shifts[i].EndTime)
-
 		for j := i + 1; j < len(st.Shifts); j++ {
-			jStartTime, _ := time.Parse("15:04:05", st.Shifts[j].StartTime)
-			jEndTime, _ := time.Parse("15:04:05", st.Shifts[j].EndTime)
+			if st.Shifts[i].Location != st.Shifts[j].Location {
+				continue
+			}
+			for _, iDay := range st.Shifts[i].ApplicableDays {
+				for _, jDay := range st.Shifts[j].ApplicableDays {
+					if ScheduleTemplateShiftsOverlapOn(&st.Shifts[i], iDay, &st.Shifts[j], jDay, st.RotationWeeks) {
+						return fmt.Errorf("班次 %d 和班次 %d 之间的时间冲突", i, j)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateScheduleTemplateShiftRequirements 检查班次按天的人数要求是否都落在班次适用的天数上，并且每天最多一条
+func ValidateScheduleTemplateShiftRequirements(st *domain.ScheduleTemplate) error {
+	for id, shift := range st.Shifts {
+		seen := make(map[int32]bool)
+		for _, requirement := range shift.DayRequirements {
+			if !slices.Contains(shift.ApplicableDays, requirement.Day) {
+				return fmt.Errorf("班次 %d 不适用于第 %d 天，不能单独设置这一天的人数", id, requirement.Day)
+			}
+			if seen[requirement.Day] {
+				return fmt.Errorf("班次 %d 的第 %d 天重复设置了人数", id, requirement.Day)
+			}
+			seen[requirement.Day] = true
 
-			if !(jStartTime.After(iEndTime) || jStartTime.Equal(iEndTime) || iStartTime.After(jEndTime) || iStartTime.Equal(jEndTime)) {
-				return fmt.Errorf("班次 %d 和班次 %d 之间的时间冲突", i, j)
+			if requirement.RequiredAssistantNumber < 1 {
+				return fmt.Errorf("班次 %d 的第 %d 天需要的人数至少为 1", id, requirement.Day)
 			}
 		}
 	}
@@ -58,161 +92,123 @@ func ValidateSchedulePlanTime(plan *domain.SchedulePlan) error {
 	return nil
 }
 
+// ValidateSubmissionWithTemplate 检查提交的空闲时间是否和模板对的上
+// 没有空的班次可以不提交，但是同一个班次只能出现一次，提交的天数也必须是班次适用的天数
 func ValidateSubmissionWithTemplate(submission *domain.AvailabilitySubmission, template *domain.ScheduleTemplate) error {
-	if len(template.Shifts) != len(submission.Items) {
-		return errors.New("提交的空闲时间中的班次数量和模板中的班次数量不匹配")
-	}
+	seen := make(map[int64]bool)
 
 	for i, item := range submission.Items {
-		isValid := false
-
-		for _, shift := range template.Shifts {
-			if shift.ID == item.ShiftID {
-				containAllDays := true
-
-				for _, day := range item.Days {
-					if !slices.Contains(shift.ApplicableDays, day) {
-						containAllDays = false
-						break
-					}
-				}
-
-				if containAllDays {
-					isValid = true
-					break
-				}
-			}
+		index := slices.IndexFunc(template.Shifts, func(shift domain.ScheduleTemplateShift) bool { return shift.ID == item.ShiftID })
+		if index == -1 {
+			return fmt.Errorf("第 %d 项不符合模板中的班次", i+1)
 		}
+		if seen[item.ShiftID] {
+			return fmt.Errorf("第 %d 项的班次重复提交", i+1)
+		}
+		seen[item.ShiftID] = true
 
-		if !isValid {
-			return fmt.Errorf("第 %d 项不符合模板中的班次", i+1)
+		for _, day := range item.Days {
+			if !slices.Contains(template.Shifts[index].ApplicableDays, day) {
+				return fmt.Errorf("第 %d 项不符合模板中的班次", i+1)
+			}
 		}
 	}
 
 	return nil
 }
 
-func ValidateSchedulingResultWithTemplate(result *domain.SchedulingResult, template *domain.ScheduleTemplate) error {
-	if len(result.Shifts) != len(template.Shifts) {
-		return errors.New("排班结果中的班次数量和模板中的班次数量不匹配")
+// ValidateSubmissionRules 检查提交的空闲时间是否满足排班计划对 role 身份的提交要求
+func ValidateSubmissionRules(submission *domain.AvailabilitySubmission, template *domain.ScheduleTemplate, rules *domain.SubmissionRules, role domain.Role) error {
+	shifts := make(map[int64]*domain.ScheduleTemplateShift, len(template.Shifts))
+	for i := range template.Shifts {
+		shifts[template.Shifts[i].ID] = &template.Shifts[i]
 	}
 
-	for _, resultShift := range result.Shifts {
-		// 找到模板中对应的班次
-		var templateShift *domain.ScheduleTemplateShift = nil
-
-		for _, shift := range template.Shifts {
-			if shift.ID == resultShift.ShiftID {
-				templateShift = &shift
+	slots, principalSlots := 0, 0
+	days := make(map[int32]bool)
+	available := make(map[int64]bool) // 至少在一天有空的班次
+	for _, item := range submission.Items {
+		shift, exists := shifts[item.ShiftID]
+		if !exists {
+			continue
+		}
+		for _, day := range item.Days {
+			slots++
+			if _, principalRequired := shift.RequirementOn(day); principalRequired {
+				principalSlots++
 			}
+			days[day] = true
+			available[shift.ID] = true
 		}
+	}
 
-		if templateShift == nil {
-			return fmt.Errorf("排班结果中的第 %d 项不存在于排班模板中", resultShift.ShiftID)
+	if rule := rules.RuleFor(role); rule != nil {
+		if slots < int(rule.MinSlots) {
+			return fmt.Errorf("%s至少需要提交 %d 个有空的班次，当前只有 %d 个", role, rule.MinSlots, slots)
 		}
-
-		for _, day := range templateShift.ApplicableDays {
-			containDay := false
-
-			for _, item := range resultShift.Items {
-				if item.Day == day {
-					containDay = true
-					break
-				}
-			}
-
-			if !containDay {
-				return fmt.Errorf("排班结果中的第 %d 项的班次存在没有提交结果的天数 %d", resultShift.ShiftID, day)
-			}
+		if principalSlots < int(rule.MinPrincipalSlots) {
+			return fmt.Errorf("%s至少需要提交 %d 个需要负责人的班次，当前只有 %d 个", role, rule.MinPrincipalSlots, principalSlots)
 		}
-
-		for _, item := range resultShift.Items {
-			if !slices.Contains(templateShift.ApplicableDays, item.Day) {
-				return fmt.Errorf("排班结果中的第 %d 项的第 %d 天不符合模板中的班次", resultShift.ShiftID, item.Day)
-			}
-			// +1 是因为负责人也算一个助理
-			if len(item.AssistantIDs)+1 > int(templateShift.RequiredAssistantNumber) {
-				return fmt.Errorf("排班结果中的第 %d 项的第 %d 天的助理人数超过了模板中的要求", resultShift.ShiftID, item.Day)
-			}
+		if len(days) < int(rule.MinDistinctDays) {
+			return fmt.Errorf("%s至少需要在 %d 天中有空，当前只有 %d 天", role, rule.MinDistinctDays, len(days))
 		}
 	}
 
-	return nil
-}
-
-func getSubmissionByAssistantID(submissions []*domain.AvailabilitySubmission, assistantID int64) *domain.AvailabilitySubmission {
-	for _, submission := range submissions {
-		if submission.UserID == assistantID {
-			return submission
+	for _, shiftID := range rules.RequiredShiftIDs {
+		shift, exists := shifts[shiftID]
+		if !exists || available[shiftID] {
+			continue
+		}
+		name := fmt.Sprintf("%s-%s", shift.StartTime, shift.EndTime)
+		if shift.Location != "" {
+			name = shift.Location + " " + name
 		}
+		return fmt.Errorf("班次 %s 要求每个人都提交，请至少选择其中一天", name)
 	}
+
 	return nil
 }
 
-func ValidateSchedulingResultWithSubmissions(result *domain.SchedulingResult, submissions []*domain.AvailabilitySubmission) error {
-	for i, shift := range result.Shifts {
-		for _, item := range shift.Items {
-			if item.PrincipalID != nil {
-				// 找到这个负责人对应的提交
-				submission := getSubmissionByAssistantID(submissions, *item.PrincipalID)
-				if submission == nil {
-					return fmt.Errorf("班次 %d 的第 %d 天的 id 为 %d 的负责人没有提交空闲时间", i+1, item.Day, item.PrincipalID)
-				}
+// ValidateSubmissionRulesWithTemplate 检查提交要求本身是否合法，必须提交的班次都要属于排班计划的模板
+func ValidateSubmissionRulesWithTemplate(rules *domain.SubmissionRules, template *domain.ScheduleTemplate) error {
+	seen := make(map[domain.Role]bool)
+	for _, rule := range rules.RoleRules {
+		if seen[rule.Role] {
+			return fmt.Errorf("%s的提交要求重复设置", rule.Role)
+		}
+		seen[rule.Role] = true
 
-				// 检查这个负责人是否在第 item.Day 天有空闲时间
-				var ok bool = false
-				for _, submissionItem := range submission.Items {
-					if submissionItem.ShiftID == shift.ShiftID && slices.Contains(submissionItem.Days, item.Day) {
-						ok = true
-						break
-					}
-				}
-				if !ok {
-					return fmt.Errorf("id 为 %d 的负责人在班次 %d 的第 %d 天没有空闲时间", item.PrincipalID, shift.ShiftID, item.Day)
-				}
-			}
-			for _, assistantID := range item.AssistantIDs {
-				// 找到这个助理对应的提交
-				submission := getSubmissionByAssistantID(submissions, assistantID)
-				if submission == nil {
-					return fmt.Errorf("班次 %d 的第 %d 天的 id 为 %d 的助理没有提交空闲时间", i+1, item.Day, assistantID)
-				}
+		if rule.MinPrincipalSlots > rule.MinSlots && rule.MinSlots > 0 {
+			return fmt.Errorf("%s需要负责人的班次数量不能多于总的班次数量", rule.Role)
+		}
+	}
 
-				// 检查这个助理是否在第 item.Day 天有空闲时间
-				var ok bool = false
-				for _, submissionItem := range submission.Items {
-					if submissionItem.ShiftID == shift.ShiftID && slices.Contains(submissionItem.Days, item.Day) {
-						ok = true
-						break
-					}
-				}
-				if !ok {
-					return fmt.Errorf("id 为 %d 的助理在班次 %d 的第 %d 天没有空闲时间", assistantID, shift.ShiftID, item.Day)
-				}
-			}
+	for _, shiftID := range rules.RequiredShiftIDs {
+		if !slices.ContainsFunc(template.Shifts, func(shift domain.ScheduleTemplateShift) bool { return shift.ID == shiftID }) {
+			return fmt.Errorf("班次 %d 不属于排班计划的模板", shiftID)
 		}
 	}
 
 	return nil
 }
 
-func ValidIfExistsDuplicateAssistant(result *domain.SchedulingResult) error {
-	// 检查是否存在某个班次中的某一天有重复的助理
-	for i, resultShift := range result.Shifts {
-		for _, resultShiftItem := range resultShift.Items {
-			// 先检查负责人是不是存在于助理数组中
-			if resultShiftItem.PrincipalID != nil && slices.Contains(resultShiftItem.AssistantIDs, *resultShiftItem.PrincipalID) {
-				return fmt.Errorf("班次 %d 的第 %d 天中负责人和助理重复", i, resultShiftItem.Day)
-			}
-			// 检查助理之间是否有重复
-			seen := make(map[int64]bool)
-			for _, assistantID := range resultShiftItem.AssistantIDs {
-				if seen[assistantID] {
-					return fmt.Errorf("班次 %d 的第 %d 天中存在重复助理", i, resultShiftItem.Day)
-				}
-				seen[assistantID] = true
-			}
+func ValidateCalendarException(exception *domain.CalendarException, plan *domain.SchedulePlan) error {
+	if exception.Date.Before(DateOf(plan.ActiveStartTime)) || exception.Date.After(DateOf(plan.ActiveEndTime)) {
+		return fmt.Errorf("日期 %s 不在排班计划的生效期间内", exception.Date.Format("2006-01-02"))
+	}
+
+	switch exception.Type {
+	case domain.CalendarExceptionTypeClosed:
+		if exception.WorkAsDay != nil {
+			return errors.New("停班的日期不能指定按照星期几排班")
 		}
+	case domain.CalendarExceptionTypeWorkday:
+		if exception.WorkAsDay == nil || *exception.WorkAsDay < 1 || *exception.WorkAsDay > 7 {
+			return errors.New("调休的日期必须指定按照星期几（1-7）排班")
+		}
+	default:
+		return fmt.Errorf("未知的例外类型 %s", exception.Type)
 	}
+
 	return nil
 }
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE shift_swap_type AS ENUM ('转让', '交换');
+
+CREATE TYPE shift_swap_status AS ENUM ('待接受', '待审批', '已完成', '已拒绝', '已驳回', '已取消');
+
+CREATE TABLE IF NOT EXISTS shift_swap_requests (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_plan_id BIGINT NOT NULL REFERENCES schedule_plans(id) ON DELETE CASCADE,
+    type shift_swap_type NOT NULL,
+    proposer_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    counterparty_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    schedule_template_shift_id BIGINT NOT NULL REFERENCES schedule_template_shifts(id) ON DELETE CASCADE,
+    day_of_week INT NOT NULL,
+    counterparty_schedule_template_shift_id BIGINT REFERENCES schedule_template_shifts(id) ON DELETE CASCADE,
+    counterparty_day_of_week INT,
+    reason TEXT NOT NULL,
+    status shift_swap_status NOT NULL DEFAULT '待接受',
+    reviewer_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shift_swap_requests;
+
+DROP TYPE IF EXISTS shift_swap_status;
+
+DROP TYPE IF EXISTS shift_swap_type;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE leave_request_status AS ENUM ('待审批', '已批准', '已驳回', '已取消');
+
+CREATE TABLE IF NOT EXISTS leave_requests (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_plan_id BIGINT NOT NULL REFERENCES schedule_plans(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    schedule_template_shift_id BIGINT NOT NULL REFERENCES schedule_template_shifts(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    reason TEXT NOT NULL,
+    status leave_request_status NOT NULL DEFAULT '待审批',
+    reviewer_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    substitute_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1
+);
+
+-- 同一个人在同一天的同一个班次只能有一个有效的请假申请
+CREATE UNIQUE INDEX IF NOT EXISTS leave_requests_active_key
+    ON leave_requests (user_id, schedule_template_shift_id, date)
+    WHERE status IN ('待审批', '已批准');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS leave_requests;
+
+DROP TYPE IF EXISTS leave_request_status;
+-- +goose StatementEnd
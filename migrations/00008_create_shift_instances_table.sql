@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE attendance_status AS ENUM ('待签到', '值班中', '正常', '迟到', '早退', '迟到且早退', '缺勤', '未签退');
+
+CREATE TABLE IF NOT EXISTS shift_instances (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_plan_id BIGINT NOT NULL REFERENCES schedule_plans(id) ON DELETE CASCADE,
+    schedule_template_shift_id BIGINT NOT NULL REFERENCES schedule_template_shifts(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    is_principal BOOLEAN NOT NULL DEFAULT FALSE,
+    start_at TIMESTAMPTZ NOT NULL,
+    end_at TIMESTAMPTZ NOT NULL,
+    check_in_at TIMESTAMPTZ,
+    check_out_at TIMESTAMPTZ,
+    status_override attendance_status, -- 黑心手动修正后的考勤状态，为空时根据签到签退时间自动计算
+    note TEXT NOT NULL DEFAULT '',
+    corrected_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1,
+    UNIQUE (schedule_plan_id, schedule_template_shift_id, date, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS shift_instances_date_idx ON shift_instances (date);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shift_instances;
+
+DROP TYPE IF EXISTS attendance_status;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE calendar_exception_type AS ENUM ('停班', '调休');
+
+CREATE TABLE IF NOT EXISTS calendar_exceptions (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_plan_id BIGINT NOT NULL REFERENCES schedule_plans(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    type calendar_exception_type NOT NULL,
+    work_as_day INT, -- 调休时当天按照星期几排班
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1,
+    UNIQUE (schedule_plan_id, date),
+    CONSTRAINT calendar_exceptions_work_as_day_check CHECK (
+        (type = '停班' AND work_as_day IS NULL) OR
+        (type = '调休' AND work_as_day BETWEEN 1 AND 7)
+    )
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS calendar_exceptions;
+
+DROP TYPE IF EXISTS calendar_exception_type;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE schedule_plan_status AS ENUM ('草稿', '收集中', '已截止', '排班中', '已发布', '生效中', '已归档');
+
+ALTER TABLE schedule_plans ADD COLUMN status schedule_plan_status NOT NULL DEFAULT '草稿';
+
+-- 根据已有的时间推断出已有排班计划的状态
+UPDATE schedule_plans sp
+SET status = CASE
+    WHEN NOW() < sp.submission_start_time THEN '草稿'
+    WHEN NOW() < sp.submission_end_time THEN '收集中'
+    WHEN NOT EXISTS (SELECT 1 FROM scheduling_results sr WHERE sr.schedule_plan_id = sp.id) THEN '已截止'
+    WHEN NOW() < sp.active_start_time THEN '已发布'
+    WHEN NOW() < sp.active_end_time THEN '生效中'
+    ELSE '已归档'
+END::schedule_plan_status;
+
+CREATE INDEX IF NOT EXISTS schedule_plans_status_idx ON schedule_plans (status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE schedule_plans DROP COLUMN IF EXISTS status;
+
+DROP TYPE IF EXISTS schedule_plan_status;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE availability_submissions
+    ADD COLUMN revision INT NOT NULL DEFAULT 1,
+    ADD COLUMN superseded_at TIMESTAMPTZ; -- 为 NULL 时表示这是该用户最新的提交
+
+ALTER TABLE availability_submissions
+    ADD CONSTRAINT availability_submissions_revision_key UNIQUE (user_id, schedule_plan_id, revision);
+
+-- 每个用户在每个排班计划中只能有一条最新的提交
+CREATE UNIQUE INDEX IF NOT EXISTS availability_submissions_latest_key
+    ON availability_submissions (user_id, schedule_plan_id)
+    WHERE superseded_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM availability_submissions WHERE superseded_at IS NOT NULL;
+
+DROP INDEX IF EXISTS availability_submissions_latest_key;
+
+ALTER TABLE availability_submissions
+    DROP CONSTRAINT IF EXISTS availability_submissions_revision_key,
+    DROP COLUMN IF EXISTS superseded_at,
+    DROP COLUMN IF EXISTS revision;
+-- +goose StatementEnd
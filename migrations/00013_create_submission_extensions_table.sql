@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS submission_extensions (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_plan_id BIGINT NOT NULL REFERENCES schedule_plans(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    deadline TIMESTAMPTZ NOT NULL, -- 该用户单独的截止时间
+    reason TEXT NOT NULL DEFAULT '',
+    granted_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1,
+    UNIQUE (schedule_plan_id, user_id)
+);
+
+CREATE TYPE late_submission_status AS ENUM ('待审批', '已批准', '已驳回');
+
+-- 为 NULL 时表示按时提交
+ALTER TABLE availability_submissions
+    ADD COLUMN late_status late_submission_status,
+    ADD COLUMN late_reviewer_id BIGINT REFERENCES users(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE availability_submissions
+    DROP COLUMN IF EXISTS late_reviewer_id,
+    DROP COLUMN IF EXISTS late_status;
+
+DROP TYPE IF EXISTS late_submission_status;
+
+DROP TABLE IF EXISTS submission_extensions;
+-- +goose StatementEnd
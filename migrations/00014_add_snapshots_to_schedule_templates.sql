@@ -0,0 +1,79 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 排班计划在创建时会冻结一份模板的快照，提交和排班结果都引用快照中的班次
+ALTER TABLE schedule_templates
+    ADD COLUMN is_snapshot BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN source_template_id BIGINT REFERENCES schedule_templates(id) ON DELETE SET NULL,
+    ADD COLUMN source_version INT; -- 创建快照时源模板的 version
+
+-- 快照沿用源模板的名称，因此名称只需要在非快照的模板之间唯一
+ALTER TABLE schedule_templates DROP CONSTRAINT IF EXISTS schedule_templates_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS schedule_templates_name_key ON schedule_templates (name) WHERE NOT is_snapshot;
+
+-- 已经被排班计划使用的模板直接转为快照，这样已有的提交和排班结果不需要重新指向新的班次
+-- 同时为每个这样的模板复制一份同名的普通模板，以便之后继续编辑和使用
+DO $$
+DECLARE
+    t RECORD;
+    s RECORD;
+    new_template_id BIGINT;
+    new_shift_id BIGINT;
+BEGIN
+    FOR t IN
+        SELECT * FROM schedule_templates
+        WHERE id IN (SELECT DISTINCT schedule_template_id FROM schedule_plans)
+    LOOP
+        UPDATE schedule_templates SET is_snapshot = TRUE, source_version = version WHERE id = t.id;
+
+        INSERT INTO schedule_templates (name, description)
+        VALUES (t.name, t.description)
+        RETURNING id INTO new_template_id;
+
+        UPDATE schedule_templates SET source_template_id = new_template_id WHERE id = t.id;
+
+        FOR s IN SELECT * FROM schedule_template_shifts WHERE template_id = t.id ORDER BY id LOOP
+            INSERT INTO schedule_template_shifts (template_id, start_time, end_time, required_assistant_number)
+            VALUES (new_template_id, s.start_time, s.end_time, s.required_assistant_number)
+            RETURNING id INTO new_shift_id;
+
+            INSERT INTO schedule_template_shift_applicable_days (shift_id, day)
+            SELECT new_shift_id, day FROM schedule_template_shift_applicable_days WHERE shift_id = s.id;
+        END LOOP;
+    END LOOP;
+END $$;
+
+-- 不允许因为删除班次而级联删除提交和排班结果
+ALTER TABLE availability_submission_items
+    DROP CONSTRAINT IF EXISTS availability_submission_items_schedule_template_shift_id_fkey,
+    ADD CONSTRAINT availability_submission_items_schedule_template_shift_id_fkey
+        FOREIGN KEY (schedule_template_shift_id) REFERENCES schedule_template_shifts(id) ON DELETE RESTRICT;
+
+ALTER TABLE scheduling_result_shifts
+    DROP CONSTRAINT IF EXISTS scheduling_result_shifts_schedule_template_shift_id_fkey,
+    ADD CONSTRAINT scheduling_result_shifts_schedule_template_shift_id_fkey
+        FOREIGN KEY (schedule_template_shift_id) REFERENCES schedule_template_shifts(id) ON DELETE RESTRICT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE scheduling_result_shifts
+    DROP CONSTRAINT IF EXISTS scheduling_result_shifts_schedule_template_shift_id_fkey,
+    ADD CONSTRAINT scheduling_result_shifts_schedule_template_shift_id_fkey
+        FOREIGN KEY (schedule_template_shift_id) REFERENCES schedule_template_shifts(id) ON DELETE CASCADE;
+
+ALTER TABLE availability_submission_items
+    DROP CONSTRAINT IF EXISTS availability_submission_items_schedule_template_shift_id_fkey,
+    ADD CONSTRAINT availability_submission_items_schedule_template_shift_id_fkey
+        FOREIGN KEY (schedule_template_shift_id) REFERENCES schedule_template_shifts(id) ON DELETE CASCADE;
+
+-- 快照保留下来作为普通模板，名称加上 ID 以避免冲突
+UPDATE schedule_templates SET name = name || '#' || id WHERE is_snapshot;
+
+DROP INDEX IF EXISTS schedule_templates_name_key;
+ALTER TABLE schedule_templates ADD CONSTRAINT schedule_templates_name_key UNIQUE (name);
+
+ALTER TABLE schedule_templates
+    DROP COLUMN IF EXISTS source_version,
+    DROP COLUMN IF EXISTS source_template_id,
+    DROP COLUMN IF EXISTS is_snapshot;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 班次在某一天单独的人数要求，没有记录的天数沿用班次本身的要求
+CREATE TABLE IF NOT EXISTS schedule_template_shift_day_requirements (
+    id BIGSERIAL PRIMARY KEY,
+    shift_id BIGINT NOT NULL REFERENCES schedule_template_shifts(id) ON DELETE CASCADE,
+    day INT NOT NULL,
+    required_assistant_number INT NOT NULL, -- 包括负责人在内
+    principal_required BOOLEAN NOT NULL DEFAULT TRUE, -- 这一天是否需要安排负责人
+    UNIQUE (shift_id, day)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS schedule_template_shift_day_requirements;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 班次所在的值班点，为空表示默认值班点
+ALTER TABLE schedule_template_shifts ADD COLUMN location TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE schedule_template_shifts DROP COLUMN IF EXISTS location;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 模板按照 rotation_weeks 周轮换，班次的适用天数、提交和排班结果中的天数都使用周期内的天数 (第几周 - 1) * 7 + 星期几
+ALTER TABLE schedule_templates ADD COLUMN rotation_weeks INT NOT NULL DEFAULT 1;
+
+-- 轮换周期第一周所在的日期，为空时以生效开始时间所在的周为第一周
+ALTER TABLE schedule_plans ADD COLUMN rotation_start_date DATE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE schedule_plans DROP COLUMN IF EXISTS rotation_start_date;
+
+ALTER TABLE schedule_templates DROP COLUMN IF EXISTS rotation_weeks;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 排班计划的参与名单，只有名单中的用户可以提交空闲时间、收到提醒和参与排班
+-- 排班计划没有设置名单时所有在职用户都可以参与
+CREATE TABLE IF NOT EXISTS schedule_plan_participants (
+    schedule_plan_id BIGINT NOT NULL REFERENCES schedule_plans(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (schedule_plan_id, user_id)
+);
+
+-- 每个排班计划实际可以参与的在职用户，统计、提醒和排班都基于这个视图
+CREATE VIEW schedule_plan_effective_participants AS
+SELECT sp.id AS schedule_plan_id, u.id AS user_id
+FROM schedule_plans sp
+CROSS JOIN users u
+WHERE u.is_active = TRUE
+    AND (
+        NOT EXISTS (SELECT 1 FROM schedule_plan_participants p WHERE p.schedule_plan_id = sp.id)
+        OR EXISTS (SELECT 1 FROM schedule_plan_participants p WHERE p.schedule_plan_id = sp.id AND p.user_id = u.id)
+    );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW IF EXISTS schedule_plan_effective_participants;
+
+DROP TABLE IF EXISTS schedule_plan_participants;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 排班计划按照身份设置的提交要求，没有记录的身份没有额外要求
+CREATE TABLE IF NOT EXISTS submission_role_rules (
+    schedule_plan_id BIGINT NOT NULL REFERENCES schedule_plans(id) ON DELETE CASCADE,
+    role user_role NOT NULL,
+    min_slots INT NOT NULL DEFAULT 0, -- 至少提交多少个有空的 (班次, 天)
+    min_principal_slots INT NOT NULL DEFAULT 0, -- 其中至少多少个是需要负责人的 (班次, 天)
+    min_distinct_days INT NOT NULL DEFAULT 0, -- 至少在多少个不同的天有空
+    PRIMARY KEY (schedule_plan_id, role)
+);
+
+-- 排班计划中每个人都必须提交空闲时间的班次，至少要在其中一天有空
+CREATE TABLE IF NOT EXISTS submission_required_shifts (
+    schedule_plan_id BIGINT NOT NULL REFERENCES schedule_plans(id) ON DELETE CASCADE,
+    shift_id BIGINT NOT NULL REFERENCES schedule_template_shifts(id) ON DELETE CASCADE,
+    PRIMARY KEY (schedule_plan_id, shift_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS submission_required_shifts;
+
+DROP TABLE IF EXISTS submission_role_rules;
+-- +goose StatementEnd
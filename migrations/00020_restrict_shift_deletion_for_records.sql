@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 具体值班、请假和换班都是历史记录，同样不允许因为删除班次而被级联删除
+-- 约束名可能因为长度被截断，因此按照引用关系查找并删除原来的外键
+DO $$
+DECLARE
+    c RECORD;
+BEGIN
+    FOR c IN
+        SELECT conrelid::regclass AS table_name, conname
+        FROM pg_constraint
+        WHERE contype = 'f'
+            AND confrelid = 'schedule_template_shifts'::regclass
+            AND conrelid IN ('shift_instances'::regclass, 'leave_requests'::regclass, 'shift_swap_requests'::regclass)
+    LOOP
+        EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I', c.table_name, c.conname);
+    END LOOP;
+END $$;
+
+ALTER TABLE shift_instances
+    ADD CONSTRAINT shift_instances_shift_fkey
+        FOREIGN KEY (schedule_template_shift_id) REFERENCES schedule_template_shifts(id) ON DELETE RESTRICT;
+
+ALTER TABLE leave_requests
+    ADD CONSTRAINT leave_requests_shift_fkey
+        FOREIGN KEY (schedule_template_shift_id) REFERENCES schedule_template_shifts(id) ON DELETE RESTRICT;
+
+ALTER TABLE shift_swap_requests
+    ADD CONSTRAINT shift_swap_requests_shift_fkey
+        FOREIGN KEY (schedule_template_shift_id) REFERENCES schedule_template_shifts(id) ON DELETE RESTRICT,
+    ADD CONSTRAINT shift_swap_requests_counterparty_shift_fkey
+        FOREIGN KEY (counterparty_schedule_template_shift_id) REFERENCES schedule_template_shifts(id) ON DELETE RESTRICT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE shift_swap_requests
+    DROP CONSTRAINT IF EXISTS shift_swap_requests_counterparty_shift_fkey,
+    DROP CONSTRAINT IF EXISTS shift_swap_requests_shift_fkey,
+    ADD CONSTRAINT shift_swap_requests_shift_fkey
+        FOREIGN KEY (schedule_template_shift_id) REFERENCES schedule_template_shifts(id) ON DELETE CASCADE,
+    ADD CONSTRAINT shift_swap_requests_counterparty_shift_fkey
+        FOREIGN KEY (counterparty_schedule_template_shift_id) REFERENCES schedule_template_shifts(id) ON DELETE CASCADE;
+
+ALTER TABLE leave_requests
+    DROP CONSTRAINT IF EXISTS leave_requests_shift_fkey,
+    ADD CONSTRAINT leave_requests_shift_fkey
+        FOREIGN KEY (schedule_template_shift_id) REFERENCES schedule_template_shifts(id) ON DELETE CASCADE;
+
+ALTER TABLE shift_instances
+    DROP CONSTRAINT IF EXISTS shift_instances_shift_fkey,
+    ADD CONSTRAINT shift_instances_shift_fkey
+        FOREIGN KEY (schedule_template_shift_id) REFERENCES schedule_template_shifts(id) ON DELETE CASCADE;
+-- +goose StatementEnd